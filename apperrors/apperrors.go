@@ -0,0 +1,171 @@
+// Package apperrors define los errores de dominio que devuelven los servicios
+// y repositorios. Cada error tiene un tipo, que determina el status HTTP, y un
+// codigo estable que los clientes pueden interpretar sin depender del texto
+// del mensaje.
+package apperrors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"qisur-challenge/i18n"
+)
+
+// Kind clasifica los errores segun como debe responderlos la API.
+type Kind int
+
+const (
+	KindInternal Kind = iota
+	KindNotFound
+	KindConflict
+	KindValidation
+	KindUnauthorized
+	KindForbidden
+	KindTooLarge
+	KindTimeout
+	KindUnavailable
+	KindMethodNotAllowed
+)
+
+// Status devuelve el status HTTP que corresponde al tipo de error.
+func (k Kind) Status() int {
+	switch k {
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindConflict:
+		return http.StatusConflict
+	case KindValidation:
+		return http.StatusBadRequest
+	case KindUnauthorized:
+		return http.StatusUnauthorized
+	case KindForbidden:
+		return http.StatusForbidden
+	case KindTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case KindTimeout:
+		return http.StatusGatewayTimeout
+	case KindUnavailable:
+		return http.StatusServiceUnavailable
+	case KindMethodNotAllowed:
+		return http.StatusMethodNotAllowed
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Error es un error de dominio. Message es el texto para el usuario, en
+// espanol, y Details los datos adicionales que se devuelven al cliente (por
+// ejemplo el campo invalido). Key es la clave del mensaje en el catalogo de
+// traducciones cuando el texto depende del caso; si esta vacia se usa Code.
+// Err es la causa, si la hay, y no se expone en la respuesta.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Key     string
+	Message string
+	Details map[string]any
+	Err     error
+}
+
+func New(kind Kind, code, format string, args ...any) *Error {
+	return &Error{Kind: kind, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func NotFound(code, format string, args ...any) *Error {
+	return New(KindNotFound, code, format, args...)
+}
+
+func Conflict(code, format string, args ...any) *Error {
+	return New(KindConflict, code, format, args...)
+}
+
+func Validation(code, format string, args ...any) *Error {
+	return New(KindValidation, code, format, args...)
+}
+
+func Unauthorized(code, format string, args ...any) *Error {
+	return New(KindUnauthorized, code, format, args...)
+}
+
+func Forbidden(code, format string, args ...any) *Error {
+	return New(KindForbidden, code, format, args...)
+}
+
+// Internal devuelve err sin cambios si ya es un error de dominio y, si no, lo
+// envuelve como error interno con el mensaje indicado.
+func Internal(err error, format string, args ...any) error {
+	if _, ok := As(err); ok {
+		return err
+	}
+	return New(KindInternal, CodeInternal, format, args...).Wrap(err)
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is compara por codigo, para poder usar errores de dominio como sentinelas
+// con errors.Is aunque tengan otros detalles.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Wrap registra err como causa del error.
+func (e *Error) Wrap(err error) *Error {
+	e.Err = err
+	return e
+}
+
+// With agrega un dato a los detalles del error.
+func (e *Error) With(key string, value any) *Error {
+	if e.Details == nil {
+		e.Details = map[string]any{}
+	}
+	e.Details[key] = value
+	return e
+}
+
+// WithKey indica la variante del mensaje que se usa para traducirlo.
+func (e *Error) WithKey(key string) *Error {
+	e.Key = key
+	return e
+}
+
+// Localize devuelve el mensaje en lang. Los demas idiomas usan el catalogo,
+// con Details como parametros, y si no hay traduccion se usa Message.
+func (e *Error) Localize(lang i18n.Lang) string {
+	if lang == i18n.Default {
+		return e.Message
+	}
+	if e.Key != "" {
+		if msg, ok := i18n.Translate(lang, e.Key, e.Details); ok {
+			return msg
+		}
+	}
+	if msg, ok := i18n.Translate(lang, e.Code, e.Details); ok {
+		return msg
+	}
+	return e.Message
+}
+
+// As devuelve el error de dominio contenido en err, si lo hay.
+func As(err error) (*Error, bool) {
+	var appErr *Error
+	ok := errors.As(err, &appErr)
+	return appErr, ok
+}
+
+// KindOf devuelve el tipo del error de dominio contenido en err, o
+// KindInternal si no contiene ninguno.
+func KindOf(err error) Kind {
+	if appErr, ok := As(err); ok {
+		return appErr.Kind
+	}
+	return KindInternal
+}
@@ -0,0 +1,61 @@
+package apperrors
+
+// Codigos de error que devuelve la API. Son parte del contrato con los
+// clientes: no cambian aunque cambie el mensaje.
+const (
+	CodeInternal         = "internal_error"
+	CodeInvalidID        = "invalid_id"
+	CodeInvalidBody      = "invalid_body"
+	CodeInvalidParameter = "invalid_parameter"
+	CodeValidation       = "validation_failed"
+	CodeInvalidFile      = "invalid_file"
+	CodeUnauthorized     = "unauthorized"
+	CodeInvalidToken     = "invalid_token"
+	CodeForbidden        = "forbidden"
+	CodePayloadTooLarge  = "payload_too_large"
+	CodeQueryTimeout     = "query_timeout"
+	CodeUnavailable      = "service_unavailable"
+	CodeRouteNotFound    = "route_not_found"
+	CodeMethodNotAllowed = "method_not_allowed"
+
+	CodeInvalidCredentials = "invalid_credentials"
+	CodeUserExists         = "user_already_exists"
+	CodeUserNotFound       = "user_not_found"
+
+	CodeProductNotFound   = "product_not_found"
+	CodeProductNameTaken  = "product_name_taken"
+	CodeHistoryNotFound   = "history_not_found"
+	CodeCategoryNotFound  = "category_not_found"
+	CodeCategoryNameTaken = "category_name_taken"
+
+	CodeJobNotFound        = "job_not_found"
+	CodeJobNotDownloadable = "job_not_downloadable"
+	CodeJobNotFinished     = "job_not_finished"
+	CodeJobResultMissing   = "job_result_missing"
+
+	CodeSchedulerDisabled = "scheduler_disabled"
+	CodeWebSocketFull     = "websocket_full"
+	CodeInvalidMessage    = "invalid_message"
+	CodeUnknownMessage    = "unknown_message_type"
+)
+
+// Variantes de mensaje de los codigos cuyo texto depende del caso. Son las
+// claves del catalogo de traducciones (ver Error.WithKey) y no se devuelven
+// al cliente.
+const (
+	KeyParameterFormat  = CodeInvalidParameter + ".format"
+	KeyParameterAllowed = CodeInvalidParameter + ".allowed"
+	KeyParameterValue   = CodeInvalidParameter + ".value"
+	KeyParameterRange   = CodeInvalidParameter + ".range"
+
+	KeyPriceRequired       = CodeValidation + ".price"
+	KeyEffectiveAtRequired = CodeValidation + ".effective_at"
+	KeyMaxIntervals        = CodeValidation + ".max_intervals"
+	KeyCredentialsRequired = CodeValidation + ".credentials"
+	KeyUnsupportedLanguage = CodeValidation + ".language"
+	KeyUnsupportedRole     = CodeValidation + ".role"
+
+	KeyNameColumnMissing = CodeInvalidFile + ".name_column"
+	KeyProductNotFoundAt = CodeProductNotFound + ".as_of"
+	KeyStarting          = CodeUnavailable + ".starting"
+)
@@ -0,0 +1,48 @@
+package apperrors
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"qisur-challenge/i18n"
+	"qisur-challenge/logger"
+)
+
+// Response es el cuerpo de todas las respuestas de error de la API.
+type Response struct {
+	Code      string         `json:"code"`
+	Message   string         `json:"message"`
+	Details   map[string]any `json:"details,omitempty"`
+	RequestID string         `json:"request_id,omitempty"`
+}
+
+// NewResponse arma el cuerpo de la respuesta para err, con el mensaje en el
+// idioma de ctx. Los errores que no son de dominio se responden como error
+// interno, sin exponer su texto.
+func NewResponse(ctx context.Context, err error) (int, Response) {
+	appErr, ok := As(err)
+	if !ok {
+		appErr = New(KindInternal, CodeInternal, "Error interno del servidor").Wrap(err)
+	}
+	if appErr.Kind == KindInternal {
+		logger.FromContext(ctx).Error("Error interno", "code", appErr.Code, logger.Err(err))
+	}
+	return appErr.Kind.Status(), Response{
+		Code:      appErr.Code,
+		Message:   appErr.Localize(i18n.FromContext(ctx)),
+		Details:   appErr.Details,
+		RequestID: logger.RequestID(ctx),
+	}
+}
+
+// Write responde err con el status que le corresponde y el cuerpo JSON
+// comun a todos los errores.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	status, body := NewResponse(r.Context(), err)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Language", string(i18n.FromContext(r.Context())))
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
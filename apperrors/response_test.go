@@ -0,0 +1,160 @@
+package apperrors_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"qisur-challenge/apperrors"
+	"qisur-challenge/i18n"
+	"qisur-challenge/logger"
+
+	"gorm.io/gorm"
+)
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantStatus  int
+		wantCode    string
+		wantMessage string
+		wantDetails map[string]any
+	}{
+		{
+			name:        "no encontrado",
+			err:         apperrors.NotFound(apperrors.CodeProductNotFound, "Producto no encontrado").Wrap(gorm.ErrRecordNotFound),
+			wantStatus:  http.StatusNotFound,
+			wantCode:    apperrors.CodeProductNotFound,
+			wantMessage: "Producto no encontrado",
+		},
+		{
+			name:        "conflicto envuelto",
+			err:         fmt.Errorf("guardando: %w", apperrors.Conflict(apperrors.CodeProductNameTaken, "producto con nombre '%s' ya existe", "Mouse").With("name", "Mouse")),
+			wantStatus:  http.StatusConflict,
+			wantCode:    apperrors.CodeProductNameTaken,
+			wantMessage: "producto con nombre 'Mouse' ya existe",
+			wantDetails: map[string]any{"name": "Mouse"},
+		},
+		{
+			name:        "prohibido",
+			err:         apperrors.Forbidden(apperrors.CodeForbidden, "No tiene permiso para realizar esta acción"),
+			wantStatus:  http.StatusForbidden,
+			wantCode:    apperrors.CodeForbidden,
+			wantMessage: "No tiene permiso para realizar esta acción",
+		},
+		{
+			name:        "error sin tipo",
+			err:         errors.New("pq: connection refused"),
+			wantStatus:  http.StatusInternalServerError,
+			wantCode:    apperrors.CodeInternal,
+			wantMessage: "Error interno del servidor",
+		},
+		{
+			name:        "error interno con mensaje",
+			err:         apperrors.Internal(errors.New("pq: connection refused"), "Error al obtener productos"),
+			wantStatus:  http.StatusInternalServerError,
+			wantCode:    apperrors.CodeInternal,
+			wantMessage: "Error al obtener productos",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/products/1", nil)
+			req = req.WithContext(logger.WithRequestID(req.Context(), "abc123"))
+			rec := httptest.NewRecorder()
+
+			apperrors.Write(rec, req, tt.err)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, se esperaba %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Content-Type"); got != "application/json" {
+				t.Errorf("Content-Type = %q", got)
+			}
+			if strings.Contains(rec.Body.String(), "pq:") {
+				t.Errorf("la respuesta expone la causa interna: %s", rec.Body.String())
+			}
+			var body apperrors.Response
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Code != tt.wantCode || body.Message != tt.wantMessage || body.RequestID != "abc123" {
+				t.Errorf("body = %+v", body)
+			}
+			if len(body.Details) != len(tt.wantDetails) {
+				t.Errorf("details = %v, se esperaba %v", body.Details, tt.wantDetails)
+			}
+			for k, v := range tt.wantDetails {
+				if body.Details[k] != v {
+					t.Errorf("details[%s] = %v, se esperaba %v", k, body.Details[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestInternalKeepsDomainErrors(t *testing.T) {
+	notFound := apperrors.NotFound(apperrors.CodeCategoryNotFound, "Categoría no encontrada").Wrap(gorm.ErrRecordNotFound)
+	err := apperrors.Internal(fmt.Errorf("actualizando: %w", notFound), "Error al actualizar categoría")
+
+	if apperrors.KindOf(err) != apperrors.KindNotFound {
+		t.Errorf("KindOf = %v, se esperaba KindNotFound", apperrors.KindOf(err))
+	}
+	if !errors.Is(err, &apperrors.Error{Code: apperrors.CodeCategoryNotFound}) || !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("el error debe conservar el codigo y la causa: %v", err)
+	}
+}
+
+func TestWriteLocalized(t *testing.T) {
+	conflict := apperrors.Conflict(apperrors.CodeProductNameTaken, "producto con nombre '%s' ya existe", "Mouse").With("name", "Mouse")
+	tests := []struct {
+		name        string
+		lang        i18n.Lang
+		err         error
+		wantMessage string
+	}{
+		{name: "espanol usa el mensaje del servicio", lang: i18n.Spanish, err: conflict, wantMessage: "producto con nombre 'Mouse' ya existe"},
+		{name: "ingles usa el catalogo con los detalles", lang: i18n.English, err: conflict, wantMessage: "A product named 'Mouse' already exists"},
+		{
+			name:        "ingles usa la variante del mensaje",
+			lang:        i18n.English,
+			err:         apperrors.Validation(apperrors.CodeValidation, "el rango solicitado supera los %d intervalos", 500).With("max_intervals", 500).WithKey(apperrors.KeyMaxIntervals),
+			wantMessage: "The requested range exceeds 500 intervals",
+		},
+		{
+			name:        "variante sin traduccion usa el mensaje del codigo",
+			lang:        i18n.English,
+			err:         apperrors.Validation(apperrors.CodeInvalidParameter, "Parámetro 'history_id' inválido").With("parameter", "history_id").WithKey("invalid_parameter.otra"),
+			wantMessage: "Invalid parameter 'history_id'",
+		},
+		{
+			name:        "error interno en ingles es generico",
+			lang:        i18n.English,
+			err:         apperrors.Internal(errors.New("pq: connection refused"), "Error al obtener productos"),
+			wantMessage: "Internal server error",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/products", nil)
+			req = req.WithContext(i18n.WithLang(req.Context(), tt.lang))
+			rec := httptest.NewRecorder()
+
+			apperrors.Write(rec, req, tt.err)
+			var body apperrors.Response
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Message != tt.wantMessage {
+				t.Errorf("message = %q, se esperaba %q", body.Message, tt.wantMessage)
+			}
+			if got := rec.Header().Get("Content-Language"); got != string(tt.lang) {
+				t.Errorf("Content-Language = %q, se esperaba %q", got, tt.lang)
+			}
+		})
+	}
+}
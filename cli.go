@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"qisur-challenge/config"
+
+	"gorm.io/gorm"
+)
+
+// command es un subcomando del binario. Los subcomandos con varias palabras
+// (por ejemplo "user create") se registran con su nombre completo.
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, args []string) error
+}
+
+var commands = map[string]command{}
+
+func registerCommand(c command) {
+	commands[c.name] = c
+}
+
+// runCommand busca el subcomando mas largo que coincida con los argumentos y
+// lo ejecuta con el resto de ellos. ctx se cancela al recibir SIGINT o SIGTERM.
+func runCommand(ctx context.Context, args []string) error {
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printUsage()
+		return nil
+	}
+	for n := len(args); n > 0; n-- {
+		if c, ok := commands[strings.Join(args[:n], " ")]; ok {
+			err := c.run(ctx, args[n:])
+			if errors.Is(err, flag.ErrHelp) {
+				return nil
+			}
+			return err
+		}
+	}
+	printUsage()
+	return fmt.Errorf("subcomando desconocido: %s", strings.Join(args, " "))
+}
+
+func printUsage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(os.Stderr, "Uso: qisur-challenge <subcomando> [opciones]")
+	fmt.Fprintln(os.Stderr, "\nSubcomandos:")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-16s %s\n", name, commands[name].usage)
+	}
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Uso de %s: %s\n", name, commands[name].usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func openDB(ctx context.Context) (*gorm.DB, error) {
+	db, err := config.CONNECTDB(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo conectar a la base de datos: %w", err)
+	}
+	return db, nil
+}
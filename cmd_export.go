@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"qisur-challenge/services"
+)
+
+func init() {
+	registerCommand(command{
+		name:  "export",
+		usage: "exporta el catálogo: export [-format csv|ndjson|xlsx] [-out archivo] [-columns id,name] [-name texto] [-sort price_asc]",
+		run:   runExport,
+	})
+}
+
+func runExport(ctx context.Context, args []string) error {
+	fs := newFlagSet("export")
+	format := fs.String("format", "csv", "formato de salida: csv, ndjson o xlsx")
+	out := fs.String("out", "", "archivo de salida (por defecto la salida estándar)")
+	columns := fs.String("columns", "", "columnas a exportar separadas por coma")
+	name := fs.String("name", "", "filtra por nombre")
+	sort := fs.String("sort", "", "orden: price_asc o price_desc")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	opts := services.ExportOptions{Format: *format, Name: *name, Sort: *sort}
+	if *columns != "" {
+		opts.Columns = strings.Split(*columns, ",")
+	}
+
+	w := os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	db, err := openDB(ctx)
+	if err != nil {
+		return err
+	}
+	return services.NewProductService(db).ExportProducts(ctx, w, opts)
+}
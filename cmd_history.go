@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"qisur-challenge/config"
+	"qisur-challenge/services"
+)
+
+func init() {
+	registerCommand(command{
+		name:  "history prune",
+		usage: "aplica la política de retención del historial: history prune [-dry-run] [-keep-days N] [-delete-months M]",
+		run:   runHistoryPrune,
+	})
+}
+
+func runHistoryPrune(ctx context.Context, args []string) error {
+	fs := newFlagSet("history prune")
+	dryRun := fs.Bool("dry-run", false, "solo informa lo que se eliminaría")
+	keepDays := fs.Int("keep-days", config.AppConfig.History.KeepDays, "días de historial completo")
+	deleteMonths := fs.Int("delete-months", config.AppConfig.History.DeleteMonths, "meses a partir de los cuales se elimina el historial")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	db, err := openDB(ctx)
+	if err != nil {
+		return err
+	}
+
+	retentionService := services.NewHistoryRetentionService(db, services.RetentionPolicy{
+		KeepDays:     *keepDays,
+		DeleteMonths: *deleteMonths,
+	})
+	report, err := retentionService.Prune(ctx, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("dry_run=%t eliminadas=%d reducidas=%d grupos=%d\n",
+		report.DryRun, report.Deleted, report.Downsampled, report.CompactedGroups)
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"qisur-challenge/services"
+)
+
+func init() {
+	registerCommand(command{
+		name:  "import",
+		usage: "importa productos desde CSV o NDJSON: import -file productos.csv [-format csv|ndjson] [-commit]",
+		run:   runImport,
+	})
+}
+
+func runImport(ctx context.Context, args []string) error {
+	fs := newFlagSet("import")
+	file := fs.String("file", "", "archivo a importar")
+	format := fs.String("format", "", "formato del archivo (por defecto según la extensión)")
+	commit := fs.Bool("commit", false, "aplica los cambios; sin este flag solo se valida (dry-run)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		return errors.New("el parámetro -file es obligatorio")
+	}
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*file)), ".")
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	db, err := openDB(ctx)
+	if err != nil {
+		return err
+	}
+
+	report, err := services.NewProductService(db).ImportProducts(ctx, f, services.ImportOptions{
+		Format: *format,
+		DryRun: !*commit,
+		Actor:  "cli",
+	})
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
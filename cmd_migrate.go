@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"qisur-challenge/migrations"
+)
+
+func init() {
+	registerCommand(command{
+		name:  "migrate",
+		usage: "gestiona el esquema: migrate up|down [pasos]|status",
+		run:   runMigrate,
+	})
+}
+
+const migrateUsage = "uso: migrate up|down [pasos]|status"
+
+// runMigrate implementa el subcomando `migrate up|down|status`.
+func runMigrate(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	db, err := openDB(ctx)
+	if err != nil {
+		return err
+	}
+	db = db.WithContext(ctx)
+
+	switch args[0] {
+	case "up":
+		applied, err := migrations.Up(db)
+		for _, m := range applied {
+			fmt.Printf("aplicada %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("No hay migraciones pendientes.")
+		}
+		return nil
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("cantidad de pasos inválida: %s", args[1])
+			}
+			steps = n
+		}
+		reverted, err := migrations.Down(db, steps)
+		for _, m := range reverted {
+			fmt.Printf("revertida %04d_%s\n", m.Version, m.Name)
+		}
+		return err
+	case "status":
+		status, err := migrations.GetStatus(db)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNOMBRE\tESTADO\tAPLICADA")
+		for _, s := range status {
+			state, appliedAt := "pendiente", "-"
+			if s.Applied {
+				state = "aplicada"
+				appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(tw, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+		}
+		return tw.Flush()
+	default:
+		return errors.New(migrateUsage)
+	}
+}
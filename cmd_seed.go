@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"qisur-challenge/seed"
+)
+
+func init() {
+	registerCommand(command{
+		name:  "seed",
+		usage: "carga datos de ejemplo: seed [-file fixture.yaml|.json] [-generate N -seed S]",
+		run:   runSeed,
+	})
+}
+
+func runSeed(ctx context.Context, args []string) error {
+	fs := newFlagSet("seed")
+	file := fs.String("file", seed.DefaultFixture, "fixture YAML o JSON a cargar")
+	generate := fs.Int("generate", 0, "genera N productos sintéticos en lugar de cargar un fixture")
+	rndSeed := fs.Int64("seed", 1, "semilla del generador de productos sintéticos")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var fixture *seed.Fixture
+	if *generate > 0 {
+		fixture = seed.Generate(*generate, *rndSeed)
+	} else {
+		var err error
+		fixture, err = seed.LoadFile(*file)
+		if err != nil {
+			return err
+		}
+	}
+
+	db, err := openDB(ctx)
+	if err != nil {
+		return err
+	}
+
+	result, err := seed.Apply(db.WithContext(ctx), fixture)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("categorías: %d creadas, %d actualizadas\n", result.CategoriesCreated, result.CategoriesUpdated)
+	fmt.Printf("productos: %d creados, %d actualizados\n", result.ProductsCreated, result.ProductsUpdated)
+	fmt.Printf("historial: %d entradas creadas\n", result.HistoryCreated)
+	return nil
+}
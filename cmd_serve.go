@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"qisur-challenge/config"
+	"qisur-challenge/dbreplica"
+	"qisur-challenge/health"
+	"qisur-challenge/jobs"
+	"qisur-challenge/logger"
+	"qisur-challenge/metrics"
+	"qisur-challenge/middlewares"
+	"qisur-challenge/migrations"
+	"qisur-challenge/routes"
+	"qisur-challenge/scheduler"
+	"qisur-challenge/services"
+	"qisur-challenge/tracing"
+	websocket "qisur-challenge/webSocket"
+)
+
+func init() {
+	registerCommand(command{
+		name:  "serve",
+		usage: "inicia el servidor HTTP y WebSocket (subcomando por defecto)",
+		run:   runServe,
+	})
+}
+
+func runServe(ctx context.Context, args []string) error {
+	fs := newFlagSet("serve")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	db, err := openDB(ctx)
+	if err != nil {
+		return err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := sqlDB.Close(); err != nil {
+			slog.Error("Error al cerrar la conexión a la base de datos", logger.Err(err))
+		}
+		if router := dbreplica.FromDB(db); router != nil {
+			if err := router.Close(); err != nil {
+				slog.Error("Error al cerrar la conexión a las réplicas", logger.Err(err))
+			}
+		}
+	}()
+
+	if err := metrics.RegisterDB(db); err != nil {
+		return err
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:    config.AppConfig.Tracing.Exporter,
+		SampleRatio: config.AppConfig.Tracing.SampleRatio,
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Error("Error al enviar las trazas pendientes", logger.Err(err))
+		}
+	}()
+	if err := tracing.RegisterDB(db); err != nil {
+		return err
+	}
+
+	retentionService := services.NewHistoryRetentionService(db, services.RetentionPolicy{
+		KeepDays:     config.AppConfig.History.KeepDays,
+		DeleteMonths: config.AppConfig.History.DeleteMonths,
+	})
+	productService := services.NewProductService(db)
+
+	jobRunner := jobs.NewRunner(db, config.AppConfig.Jobs.Workers, config.AppConfig.Jobs.PollInterval)
+	jobs.RegisterProductHandlers(jobRunner, productService, config.AppConfig.Jobs.ExportDir)
+
+	var sched *scheduler.Scheduler
+	if config.AppConfig.Scheduler.Enabled {
+		sched = scheduler.New(db)
+		if err := registerTasks(sched, retentionService, productService); err != nil {
+			return err
+		}
+	}
+
+	checker := health.NewChecker(db)
+	r := routes.RegisterRoutes(db, checker, retentionService, sched, jobRunner, config.AppConfig.Jobs.ExportDir)
+
+	// CORS envuelve al router para poder responder las preflight.
+	handler := middlewares.CORSMiddleware(config.AppConfig.CORS)(r)
+
+	srv := &http.Server{
+		Addr:              ":" + config.AppConfig.Server.Port,
+		Handler:           handler,
+		ReadTimeout:       config.AppConfig.Server.ReadTimeout,
+		ReadHeaderTimeout: config.AppConfig.Server.ReadHeaderTimeout,
+		WriteTimeout:      config.AppConfig.Server.WriteTimeout,
+		IdleTimeout:       config.AppConfig.Server.IdleTimeout,
+		MaxHeaderBytes:    config.AppConfig.Server.MaxHeaderBytes,
+	}
+	// Shutdown no cierra las conexiones WebSocket porque ya fueron tomadas
+	// por el handler, asi que se cierran aparte avisando a los clientes.
+	srv.RegisterOnShutdown(websocket.GetEventManager().CloseAll)
+	websocket.Configure(config.AppConfig.WebSocket, config.AppConfig.CORS)
+
+	// El servidor escucha desde el arranque para que /healthz responda, pero
+	// /readyz informa "starting" hasta que terminan las migraciones.
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("Servidor iniciado", "port", config.AppConfig.Server.Port)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	applied, err := migrations.Up(db.WithContext(ctx))
+	if err != nil {
+		shutdownServer(srv)
+		return err
+	}
+	slog.Info("Migraciones completadas", "applied", len(applied))
+
+	jobRunner.Start()
+	defer jobRunner.Stop()
+
+	if sched != nil {
+		if err := sched.Start(); err != nil {
+			shutdownServer(srv)
+			return err
+		}
+		defer sched.Stop()
+	}
+
+	checker.SetPhase(health.PhaseReady)
+
+	select {
+	case err := <-serveErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	// Los recursos se liberan en orden inverso con los defer: primero se
+	// drenan las requests, despues el scheduler y los workers, y al final la
+	// base de datos.
+	checker.SetPhase(health.PhaseStopping)
+	shutdownServer(srv)
+	return nil
+}
+
+func shutdownServer(srv *http.Server) {
+	slog.Info("Apagando el servidor", "timeout", config.AppConfig.Server.ShutdownTimeout.String())
+	ctx, cancel := context.WithTimeout(context.Background(), config.AppConfig.Server.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		slog.Warn("No se pudieron drenar todas las requests", logger.Err(err))
+		srv.Close()
+	}
+	slog.Info("Servidor detenido")
+}
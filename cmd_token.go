@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"qisur-challenge/models"
+	"qisur-challenge/services"
+)
+
+func init() {
+	registerCommand(command{
+		name:  "token mint",
+		usage: "genera un JWT: token mint -username <usuario> [-role admin] [-ttl 1h] [-language en]",
+		run:   runTokenMint,
+	})
+}
+
+func runTokenMint(_ context.Context, args []string) error {
+	fs := newFlagSet("token mint")
+	username := fs.String("username", "", "usuario para el que se genera el token")
+	role := fs.String("role", "user", "rol del usuario (admin, user)")
+	ttl := fs.Duration("ttl", time.Hour, "duración del token")
+	language := fs.String("language", "", "idioma de las respuestas para el token (es, en)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *username == "" {
+		return errors.New("el parámetro -username es obligatorio")
+	}
+	if *role != models.RoleAdmin && *role != models.RoleUser {
+		return fmt.Errorf("rol '%s' inválido", *role)
+	}
+
+	token, err := services.GenerateToken(*username, *role, *language, *ttl)
+	if err != nil {
+		return err
+	}
+	fmt.Println(token)
+	return nil
+}
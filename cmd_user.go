@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"qisur-challenge/services"
+)
+
+func init() {
+	registerCommand(command{
+		name:  "user create",
+		usage: "crea un usuario: user create -username <usuario> -password <contraseña> [-role admin] [-language en]",
+		run:   runUserCreate,
+	})
+	registerCommand(command{
+		name:  "user language",
+		usage: "cambia el idioma preferido: user language -username <usuario> -language <es|en|\"\">",
+		run:   runUserLanguage,
+	})
+}
+
+func runUserCreate(ctx context.Context, args []string) error {
+	fs := newFlagSet("user create")
+	username := fs.String("username", "", "nombre de usuario")
+	password := fs.String("password", "", "contraseña")
+	role := fs.String("role", "user", "rol del usuario (admin, user)")
+	language := fs.String("language", "", "idioma preferido de las respuestas (es, en)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	db, err := openDB(ctx)
+	if err != nil {
+		return err
+	}
+
+	user, err := services.NewAuthService(db).CreateUser(ctx, *username, *password, *role, *language)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Usuario '%s' creado con ID %d\n", user.Username, user.ID)
+	return nil
+}
+
+func runUserLanguage(ctx context.Context, args []string) error {
+	fs := newFlagSet("user language")
+	username := fs.String("username", "", "nombre de usuario")
+	language := fs.String("language", "", "idioma preferido (es, en); vacío para usar Accept-Language")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *username == "" {
+		return errors.New("el parámetro -username es obligatorio")
+	}
+
+	db, err := openDB(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := services.NewAuthService(db).SetLanguage(ctx, *username, *language); err != nil {
+		return err
+	}
+	fmt.Printf("Idioma de '%s' actualizado; se aplica a los tokens nuevos\n", *username)
+	return nil
+}
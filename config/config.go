@@ -1,41 +1,229 @@
 package config
 
 import (
-	"log"
 	"os"
-
-	"github.com/joho/godotenv"
+	"path/filepath"
+	"strings"
+	"time"
 )
 
+// Config es la configuracion de la aplicacion. Se arma en capas: valores por
+// defecto, archivo YAML o TOML opcional, variables de entorno y flags, en ese
+// orden de prioridad creciente. Cada campo declara su clave en el archivo
+// (yaml/toml) y su variable de entorno (env); el flag equivalente es la clave
+// completa, por ejemplo -server.port.
 type Config struct {
-	DBHost     string
-	DBUser     string
-	DBPassword string
-	DBName     string
-	DBPort     string
-	JWTSecret  string
-	ServerPort string
+	Server    ServerConfig    `yaml:"server" toml:"server"`
+	Database  DatabaseConfig  `yaml:"database" toml:"database"`
+	Auth      AuthConfig      `yaml:"auth" toml:"auth"`
+	CORS      CORSConfig      `yaml:"cors" toml:"cors"`
+	WebSocket WebSocketConfig `yaml:"websocket" toml:"websocket"`
+	Log       LogConfig       `yaml:"log" toml:"log"`
+	Tracing   TracingConfig   `yaml:"tracing" toml:"tracing"`
+	History   HistoryConfig   `yaml:"history" toml:"history"`
+	Jobs      JobsConfig      `yaml:"jobs" toml:"jobs"`
+	Scheduler SchedulerConfig `yaml:"scheduler" toml:"scheduler"`
 }
 
-var AppConfig *Config
+// ServerConfig contiene los timeouts y limites del servidor HTTP.
+type ServerConfig struct {
+	Port              string        `yaml:"port" toml:"port" env:"SERVER_PORT"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" toml:"read_timeout" env:"HTTP_READ_TIMEOUT"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" toml:"read_header_timeout" env:"HTTP_READ_HEADER_TIMEOUT"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" toml:"write_timeout" env:"HTTP_WRITE_TIMEOUT"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" toml:"idle_timeout" env:"HTTP_IDLE_TIMEOUT"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"HTTP_SHUTDOWN_TIMEOUT"`
+	MaxHeaderBytes    int           `yaml:"max_header_bytes" toml:"max_header_bytes" env:"HTTP_MAX_HEADER_BYTES"`
+	MaxBodyBytes      int64         `yaml:"max_body_bytes" toml:"max_body_bytes" env:"HTTP_MAX_BODY_BYTES"`
+}
 
-func LoadConfig() {
-	err := godotenv.Load()
-	if err != nil {
-		log.Println("No se pudo cargar el archivo .env, se usarán las variables de entorno.")
-	}
+// DatabaseConfig contiene la conexion a la base y el pool de conexiones.
+type DatabaseConfig struct {
+	// Driver es postgres o sqlite. Con sqlite solo se usa Path, que puede ser
+	// un archivo o :memory:.
+	Driver string `yaml:"driver" toml:"driver" env:"DB_DRIVER"`
+	Path   string `yaml:"path" toml:"path" env:"DB_PATH"`
+
+	Host     string `yaml:"host" toml:"host" env:"DB_HOST"`
+	Port     string `yaml:"port" toml:"port" env:"DB_PORT"`
+	User     string `yaml:"user" toml:"user" env:"DB_USER"`
+	Password string `yaml:"password" toml:"password" env:"DB_PASSWORD"`
+	Name     string `yaml:"name" toml:"name" env:"DB_NAME"`
+
+	SSLMode     string `yaml:"ssl_mode" toml:"ssl_mode" env:"DB_SSL_MODE"`
+	SSLRootCert string `yaml:"ssl_root_cert" toml:"ssl_root_cert" env:"DB_SSL_ROOT_CERT"`
+	SSLCert     string `yaml:"ssl_cert" toml:"ssl_cert" env:"DB_SSL_CERT"`
+	SSLKey      string `yaml:"ssl_key" toml:"ssl_key" env:"DB_SSL_KEY"`
+
+	// QueryTimeout es el tiempo maximo de las consultas de cada request. 0 lo desactiva.
+	QueryTimeout       time.Duration `yaml:"query_timeout" toml:"query_timeout" env:"DB_QUERY_TIMEOUT"`
+	SlowQueryThreshold time.Duration `yaml:"slow_query_threshold" toml:"slow_query_threshold" env:"DB_SLOW_QUERY_THRESHOLD"`
+
+	MaxOpenConns    int           `yaml:"max_open_conns" toml:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" toml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" toml:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME"`
+
+	// Al iniciar se intenta conectar ConnectAttempts veces, duplicando la
+	// espera desde ConnectBackoff, para tolerar que la base tarde en levantar.
+	ConnectTimeout  time.Duration `yaml:"connect_timeout" toml:"connect_timeout" env:"DB_CONNECT_TIMEOUT"`
+	ConnectAttempts int           `yaml:"connect_attempts" toml:"connect_attempts" env:"DB_CONNECT_ATTEMPTS"`
+	ConnectBackoff  time.Duration `yaml:"connect_backoff" toml:"connect_backoff" env:"DB_CONNECT_BACKOFF"`
 
-	AppConfig = &Config{
-		DBHost:     os.Getenv("DB_HOST"),
-		DBUser:     os.Getenv("DB_USER"),
-		DBPassword: os.Getenv("DB_PASSWORD"),
-		DBName:     os.Getenv("DB_NAME"),
-		DBPort:     os.Getenv("DB_PORT"),
-		JWTSecret:  os.Getenv("JWT_SECRET"),
-		ServerPort: os.Getenv("SERVER_PORT"),
+	// Reintentos de las operaciones que fallan por errores transitorios.
+	RetryAttempts int           `yaml:"retry_attempts" toml:"retry_attempts" env:"DB_RETRY_ATTEMPTS"`
+	RetryBackoff  time.Duration `yaml:"retry_backoff" toml:"retry_backoff" env:"DB_RETRY_BACKOFF"`
+
+	// Replicas de solo lectura como host o host:puerto. Usan el mismo usuario,
+	// base y TLS que la principal.
+	Replicas              []string      `yaml:"replicas" toml:"replicas" env:"DB_REPLICAS"`
+	ReplicaHealthInterval time.Duration `yaml:"replica_health_interval" toml:"replica_health_interval" env:"DB_REPLICA_HEALTH_INTERVAL"`
+}
+
+type AuthConfig struct {
+	JWTSecret string        `yaml:"jwt_secret" toml:"jwt_secret" env:"JWT_SECRET"`
+	TokenTTL  time.Duration `yaml:"token_ttl" toml:"token_ttl" env:"AUTH_TOKEN_TTL"`
+	// DefaultAdmin permite ingresar con admin/admin mientras no haya usuarios.
+	DefaultAdmin bool `yaml:"default_admin" toml:"default_admin" env:"AUTH_DEFAULT_ADMIN"`
+}
+
+// CORSConfig define que origenes pueden usar la API desde un navegador. Sin
+// origenes configurados no se envian headers CORS.
+type CORSConfig struct {
+	AllowedOrigins []string      `yaml:"allowed_origins" toml:"allowed_origins" env:"CORS_ALLOWED_ORIGINS"`
+	AllowedMethods []string      `yaml:"allowed_methods" toml:"allowed_methods" env:"CORS_ALLOWED_METHODS"`
+	AllowedHeaders []string      `yaml:"allowed_headers" toml:"allowed_headers" env:"CORS_ALLOWED_HEADERS"`
+	MaxAge         time.Duration `yaml:"max_age" toml:"max_age" env:"CORS_MAX_AGE"`
+}
+
+// AllowsOrigin indica si origin esta entre los origenes permitidos.
+func (c CORSConfig) AllowsOrigin(origin string) bool {
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
 	}
+	return false
+}
+
+type WebSocketConfig struct {
+	// MaxClients es la cantidad maxima de conexiones simultaneas. 0 no limita.
+	MaxClients      int           `yaml:"max_clients" toml:"max_clients" env:"WS_MAX_CLIENTS"`
+	MaxMessageBytes int64         `yaml:"max_message_bytes" toml:"max_message_bytes" env:"WS_MAX_MESSAGE_BYTES"`
+	WriteTimeout    time.Duration `yaml:"write_timeout" toml:"write_timeout" env:"WS_WRITE_TIMEOUT"`
+}
+
+type LogConfig struct {
+	Level  string `yaml:"level" toml:"level" env:"LOG_LEVEL"`
+	Format string `yaml:"format" toml:"format" env:"LOG_FORMAT"`
+}
+
+// TracingConfig configura las trazas de OpenTelemetry.
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter" toml:"exporter" env:"TRACING_EXPORTER"`
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio" env:"TRACING_SAMPLE_RATIO"`
+}
+
+// HistoryConfig es la retencion del historial de productos. Un valor 0
+// desactiva la etapa correspondiente.
+type HistoryConfig struct {
+	KeepDays      int    `yaml:"keep_days" toml:"keep_days" env:"HISTORY_KEEP_DAYS"`
+	DeleteMonths  int    `yaml:"delete_months" toml:"delete_months" env:"HISTORY_DELETE_MONTHS"`
+	PruneSchedule string `yaml:"prune_schedule" toml:"prune_schedule" env:"HISTORY_PRUNE_SCHEDULE"`
+	PruneDryRun   bool   `yaml:"prune_dry_run" toml:"prune_dry_run" env:"HISTORY_PRUNE_DRY_RUN"`
+}
+
+// JobsConfig configura los trabajos en segundo plano.
+type JobsConfig struct {
+	Workers      int           `yaml:"workers" toml:"workers" env:"JOB_WORKERS"`
+	PollInterval time.Duration `yaml:"poll_interval" toml:"poll_interval" env:"JOB_POLL_INTERVAL"`
+	ExportDir    string        `yaml:"export_dir" toml:"export_dir" env:"JOB_EXPORT_DIR"`
+}
+
+// SchedulerConfig configura las tareas programadas.
+type SchedulerConfig struct {
+	Enabled              bool   `yaml:"enabled" toml:"enabled" env:"SCHEDULER_ENABLED"`
+	LowStockSchedule     string `yaml:"low_stock_schedule" toml:"low_stock_schedule" env:"LOW_STOCK_SCHEDULE"`
+	LowStockThreshold    int    `yaml:"low_stock_threshold" toml:"low_stock_threshold" env:"LOW_STOCK_THRESHOLD"`
+	PriceChangesSchedule string `yaml:"price_changes_schedule" toml:"price_changes_schedule" env:"PRICE_CHANGES_SCHEDULE"`
+}
+
+var AppConfig *Config
+
+func defaultExportDir() string {
+	return filepath.Join(os.TempDir(), "qisur-exports")
+}
 
-	if AppConfig.ServerPort == "" {
-		AppConfig.ServerPort = "8080"
+// Default devuelve la configuracion por defecto, sobre la que se aplican las
+// demas capas.
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:              "8080",
+			ReadTimeout:       30 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      60 * time.Second,
+			IdleTimeout:       120 * time.Second,
+			ShutdownTimeout:   30 * time.Second,
+			MaxHeaderBytes:    1 << 20,
+			MaxBodyBytes:      1 << 20,
+		},
+		Database: DatabaseConfig{
+			Driver:                "postgres",
+			Path:                  "qisur.db",
+			Host:                  "localhost",
+			Port:                  "5432",
+			User:                  "postgres",
+			Name:                  "qisur_challenge",
+			SSLMode:               "disable",
+			QueryTimeout:          10 * time.Second,
+			SlowQueryThreshold:    200 * time.Millisecond,
+			MaxOpenConns:          25,
+			MaxIdleConns:          5,
+			ConnMaxLifetime:       30 * time.Minute,
+			ConnMaxIdleTime:       5 * time.Minute,
+			ConnectTimeout:        5 * time.Second,
+			ConnectAttempts:       5,
+			ConnectBackoff:        time.Second,
+			RetryAttempts:         3,
+			RetryBackoff:          50 * time.Millisecond,
+			ReplicaHealthInterval: 5 * time.Second,
+		},
+		Auth: AuthConfig{
+			TokenTTL:     time.Hour,
+			DefaultAdmin: true,
+		},
+		CORS: CORSConfig{
+			AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+			AllowedHeaders: []string{"Authorization", "Content-Type", "X-Request-ID"},
+			MaxAge:         10 * time.Minute,
+		},
+		WebSocket: WebSocketConfig{
+			MaxClients:      1000,
+			MaxMessageBytes: 4096,
+			WriteTimeout:    10 * time.Second,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			SampleRatio: 1,
+		},
+		History: HistoryConfig{
+			PruneSchedule: "@every 24h",
+		},
+		Jobs: JobsConfig{
+			Workers:      2,
+			PollInterval: time.Second,
+			ExportDir:    defaultExportDir(),
+		},
+		Scheduler: SchedulerConfig{
+			Enabled:              true,
+			LowStockSchedule:     "@every 1h",
+			LowStockThreshold:    5,
+			PriceChangesSchedule: "* * * * *",
+		},
 	}
 }
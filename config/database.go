@@ -1,46 +1,237 @@
 package config
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
-	"log"
-	"os"
-	"qisur-challenge/models"
+	"log/slog"
+	"math"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"qisur-challenge/dbreplica"
+	"qisur-challenge/dbretry"
+	"qisur-challenge/logger"
+
+	"github.com/glebarez/sqlite"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
 )
 
-func CONNECTDB() (*gorm.DB, error) {
-	host := os.Getenv("DB_HOST")
-	user := os.Getenv("DB_USER")
-	pass := os.Getenv("DB_PASSWORD")
-	dbname := os.Getenv("DB_NAME")
-	port := os.Getenv("DB_PORT")
+// maxConnectBackoff es la espera maxima entre dos intentos de conexion.
+const maxConnectBackoff = 30 * time.Second
 
-	log.Printf("DEBUG: host=%s user=%s pass=%s dbname=%s port=%s\n", host, user, pass, dbname, port)
+// sqliteMemoryDBs numera las bases SQLite en memoria para que cada llamada a
+// CONNECTDB tenga la suya.
+var sqliteMemoryDBs atomic.Int64
+
+// CONNECTDB abre el pool de conexiones a la base. Si Postgres todavia no
+// responde reintenta con espera exponencial segun DB_CONNECT_ATTEMPTS y
+// DB_CONNECT_BACKOFF, hasta agotar los intentos o que se cancele ctx.
+func CONNECTDB(ctx context.Context) (*gorm.DB, error) {
+	cfg := AppConfig.Database
+	dbretry.SetPolicy(dbretry.Policy{Attempts: cfg.RetryAttempts, Backoff: cfg.RetryBackoff})
+	if strings.EqualFold(cfg.Driver, "sqlite") {
+		return connectSQLite(ctx, cfg)
+	}
 
-	dsn := fmt.Sprintf(
-		"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
-		host, user, pass, dbname, port,
-	)
+	slog.Debug("Conectando a la base de datos", "host", cfg.Host, "user", cfg.User, "dbname", cfg.Name, "port", cfg.Port, "sslmode", cfg.SSLMode)
+
+	sqlDB, err := openPool(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := waitForDB(ctx, sqlDB, cfg); err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: dbretry.Wrap(sqlDB)}), &gorm.Config{Logger: newGormLogger(cfg.SlowQueryThreshold)})
 	if err != nil {
+		sqlDB.Close()
 		return nil, err
 	}
 
-	log.Println("Conexión a la base de datos exitosa")
+	if err := useReplicas(ctx, db, cfg); err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
+
+	slog.Info("Conexión a la base de datos exitosa", "host", cfg.Host, "dbname", cfg.Name, "replicas", len(cfg.Replicas))
 	return db, nil
 }
 
-func AutoMigrate(db *gorm.DB) {
-	err := db.AutoMigrate(
-		&models.Product{},
-		&models.Category{},
-		&models.ProductHistory{},
-	)
-	if err != nil {
-		log.Printf("Error al migrar modelos: %v\n", err)
+// connectSQLite abre la base SQLite de cfg.Path. Las transacciones toman el
+// lock de escritura al empezar y esperan hasta busy_timeout si otra conexion
+// lo tiene, en lugar de fallar al intentar escribir.
+func connectSQLite(ctx context.Context, cfg DatabaseConfig) (*gorm.DB, error) {
+	slog.Debug("Conectando a la base de datos", "driver", "sqlite", "path", cfg.Path)
+
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Set("_txlock", "immediate")
+
+	memory := cfg.Path == ":memory:"
+	var dsn string
+	if memory {
+		// Cada conexion a :memory: tendria una base distinta; con el VFS memdb
+		// todas las conexiones del pool comparten la misma.
+		params.Set("vfs", "memdb")
+		dsn = fmt.Sprintf("file:/qisur-%d?%s", sqliteMemoryDBs.Add(1), params.Encode())
 	} else {
-		log.Println("Migraciones completadas.")
+		params.Add("_pragma", "journal_mode(WAL)")
+		dsn = "file:" + cfg.Path + "?" + params.Encode()
+	}
+
+	sqlDB, err := sql.Open(sqlite.DriverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	if memory {
+		// La base en memoria se pierde al cerrarse la ultima conexion.
+		sqlDB.SetMaxIdleConns(max(cfg.MaxIdleConns, 1))
+		sqlDB.SetConnMaxLifetime(0)
+		sqlDB.SetConnMaxIdleTime(0)
+	}
+	if err := sqlDB.PingContext(ctx); err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
+
+	db, err := gorm.Open(sqlite.Dialector{Conn: dbretry.Wrap(sqlDB)}, &gorm.Config{Logger: newGormLogger(cfg.SlowQueryThreshold)})
+	if err != nil {
+		sqlDB.Close()
+		return nil, err
 	}
+	slog.Info("Conexión a la base de datos exitosa", "driver", "sqlite", "path", cfg.Path)
+	return db, nil
+}
+
+// openPool crea el pool de conexiones sin conectarse todavia.
+func openPool(cfg DatabaseConfig) (*sql.DB, error) {
+	pgxConfig, err := pgx.ParseConfig(buildDSN(cfg))
+	if err != nil {
+		return nil, err
+	}
+	sqlDB := stdlib.OpenDB(*pgxConfig)
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	return sqlDB, nil
+}
+
+// useReplicas registra las replicas de lectura. No se espera a que respondan:
+// una replica caida solo hace que las lecturas vayan a la principal. Las
+// verificaciones siguen hasta que se cancele ctx.
+func useReplicas(ctx context.Context, db *gorm.DB, cfg DatabaseConfig) error {
+	if len(cfg.Replicas) == 0 {
+		return nil
+	}
+	replicas := make([]dbreplica.Replica, 0, len(cfg.Replicas))
+	for _, address := range cfg.Replicas {
+		replicaCfg := cfg
+		replicaCfg.Host, replicaCfg.Port = splitReplica(address, cfg.Port)
+		sqlDB, err := openPool(replicaCfg)
+		if err != nil {
+			return fmt.Errorf("réplica %s: %w", address, err)
+		}
+		replicas = append(replicas, dbreplica.Replica{Name: address, DB: sqlDB})
+	}
+
+	router := dbreplica.New(replicas)
+	if err := db.Use(router); err != nil {
+		router.Close()
+		return err
+	}
+	router.Start(ctx, cfg.ReplicaHealthInterval)
+	return nil
+}
+
+// splitReplica separa host y puerto de una replica. Sin puerto se usa el de
+// la base principal.
+func splitReplica(address, defaultPort string) (host, port string) {
+	if h, p, err := net.SplitHostPort(address); err == nil {
+		return h, p
+	}
+	return address, defaultPort
+}
+
+// waitForDB hace ping a la base hasta que responda o se agoten los intentos.
+func waitForDB(ctx context.Context, sqlDB *sql.DB, cfg DatabaseConfig) error {
+	delay := cfg.ConnectBackoff
+	for attempt := 1; ; attempt++ {
+		err := sqlDB.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+		if attempt >= cfg.ConnectAttempts || ctx.Err() != nil {
+			return fmt.Errorf("no se pudo conectar después de %d intentos: %w", attempt, err)
+		}
+		slog.Warn("La base de datos no responde, se reintenta", "attempt", attempt, "delay", delay.String(), logger.Err(err))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		delay = min(delay*2, maxConnectBackoff)
+	}
+}
+
+// buildDSN arma la cadena de conexion, escapando los valores para admitir
+// espacios y comillas en la contraseña.
+func buildDSN(cfg DatabaseConfig) string {
+	params := []struct{ key, value string }{
+		{"host", cfg.Host},
+		{"port", cfg.Port},
+		{"user", cfg.User},
+		{"password", cfg.Password},
+		{"dbname", cfg.Name},
+		{"sslmode", cfg.SSLMode},
+		{"sslrootcert", cfg.SSLRootCert},
+		{"sslcert", cfg.SSLCert},
+		{"sslkey", cfg.SSLKey},
+	}
+	if cfg.ConnectTimeout > 0 {
+		// connect_timeout se expresa en segundos enteros y 0 significa sin limite.
+		seconds := int(math.Ceil(cfg.ConnectTimeout.Seconds()))
+		params = append(params, struct{ key, value string }{"connect_timeout", strconv.Itoa(seconds)})
+	}
+
+	parts := make([]string, 0, len(params))
+	for _, p := range params {
+		if p.value == "" && p.key != "password" {
+			continue
+		}
+		value := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(p.value)
+		parts = append(parts, fmt.Sprintf("%s='%s'", p.key, value))
+	}
+	return strings.Join(parts, " ")
+}
+
+// newGormLogger envia los logs de GORM (consultas lentas y errores) al logger
+// por defecto. Las consultas se registran sin los valores de los parametros
+// para no filtrar datos sensibles.
+func newGormLogger(slowThreshold time.Duration) gormlogger.Interface {
+	return gormlogger.New(slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn), gormlogger.Config{
+		SlowThreshold:             slowThreshold,
+		LogLevel:                  gormlogger.Warn,
+		IgnoreRecordNotFoundError: true,
+		ParameterizedQueries:      true,
+	})
 }
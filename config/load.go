@@ -0,0 +1,188 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Load arma la configuracion, la valida y la deja en AppConfig. args son los
+// argumentos del binario: se consumen los flags globales que aparecen antes
+// del subcomando y se devuelve el resto.
+func Load(args []string) ([]string, error) {
+	cfg := Default()
+	fields := cfg.fields()
+
+	fs := flag.NewFlagSet("qisur-challenge", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "archivo de configuración YAML o TOML (CONFIG_FILE)")
+	flagValues := map[string]string{}
+	for _, f := range fields {
+		key := f.key
+		fs.Func(key, "equivale a "+f.env, func(value string) error {
+			flagValues[key] = value
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *configFile != "" {
+		if err := loadFile(cfg, *configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	var errs []error
+	for _, f := range fields {
+		if value := os.Getenv(f.env); value != "" {
+			if err := f.set(value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", f.env, err))
+			}
+		}
+	}
+	// HISTORY_PRUNE_INTERVAL se mantiene por compatibilidad.
+	if interval := os.Getenv("HISTORY_PRUNE_INTERVAL"); interval != "" && os.Getenv("HISTORY_PRUNE_SCHEDULE") == "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("HISTORY_PRUNE_INTERVAL: duración inválida %q", interval))
+		} else {
+			cfg.History.PruneSchedule = "@every " + d.String()
+		}
+	}
+	for _, f := range fields {
+		if value, ok := flagValues[f.key]; ok {
+			if err := f.set(value); err != nil {
+				errs = append(errs, fmt.Errorf("-%s: %w", f.key, err))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	if cfg.Jobs.ExportDir == "" {
+		cfg.Jobs.ExportDir = defaultExportDir()
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	AppConfig = cfg
+	return fs.Args(), nil
+}
+
+// loadFile aplica sobre cfg el archivo indicado, eligiendo el formato por la
+// extension. Las claves desconocidas se rechazan para detectar errores de tipeo.
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("no se pudo leer el archivo de configuración: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(cfg); err != nil && err != io.EOF {
+			return fmt.Errorf("archivo de configuración %s: %w", path, err)
+		}
+	case ".toml":
+		meta, err := toml.Decode(string(data), cfg)
+		if err != nil {
+			return fmt.Errorf("archivo de configuración %s: %w", path, err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			keys := make([]string, len(undecoded))
+			for i, k := range undecoded {
+				keys[i] = k.String()
+			}
+			return fmt.Errorf("archivo de configuración %s: claves desconocidas: %s", path, strings.Join(keys, ", "))
+		}
+	default:
+		return fmt.Errorf("archivo de configuración %s: formato no soportado, use .yaml, .yml o .toml", path)
+	}
+	return nil
+}
+
+// field es un valor configurable con su clave (seccion.campo) y su variable de
+// entorno.
+type field struct {
+	key   string
+	env   string
+	value reflect.Value
+}
+
+// fields recorre las secciones de cfg y devuelve los campos configurables.
+func (cfg *Config) fields() []field {
+	var fields []field
+	root := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < root.NumField(); i++ {
+		section := root.Type().Field(i)
+		for j := 0; j < section.Type.NumField(); j++ {
+			f := section.Type.Field(j)
+			fields = append(fields, field{
+				key:   section.Tag.Get("yaml") + "." + f.Tag.Get("yaml"),
+				env:   f.Tag.Get("env"),
+				value: root.Field(i).Field(j),
+			})
+		}
+	}
+	return fields
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// set interpreta value segun el tipo del campo.
+func (f field) set(value string) error {
+	value = strings.TrimSpace(value)
+	switch {
+	case f.value.Type() == durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("duración inválida %q (por ejemplo 30s o 5m)", value)
+		}
+		f.value.SetInt(int64(d))
+	case f.value.Kind() == reflect.String:
+		f.value.SetString(value)
+	case f.value.Kind() == reflect.Int || f.value.Kind() == reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("se esperaba un número entero y se recibió %q", value)
+		}
+		f.value.SetInt(n)
+	case f.value.Kind() == reflect.Float64:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("se esperaba un número y se recibió %q", value)
+		}
+		f.value.SetFloat(n)
+	case f.value.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("se esperaba true o false y se recibió %q", value)
+		}
+		f.value.SetBool(b)
+	case f.value.Kind() == reflect.Slice:
+		items := []string{}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		f.value.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("tipo de configuración no soportado: %s", f.value.Type())
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// validator acumula los errores de validacion para informarlos todos juntos,
+// indicando la clave y la variable de entorno de cada uno.
+type validator struct {
+	envs map[string]string
+	errs []error
+}
+
+func (v *validator) check(ok bool, key, format string, args ...interface{}) {
+	if ok {
+		return
+	}
+	v.errs = append(v.errs, fmt.Errorf("%s (%s): %s", key, v.envs[key], fmt.Sprintf(format, args...)))
+}
+
+// Validate verifica que la configuracion sea consistente antes de iniciar.
+func (cfg *Config) Validate() error {
+	v := &validator{envs: map[string]string{}}
+	for _, f := range cfg.fields() {
+		v.envs[f.key] = f.env
+	}
+
+	s := cfg.Server
+	v.check(isPort(s.Port), "server.port", "debe ser un puerto entre 1 y 65535")
+	v.check(s.ReadTimeout >= 0, "server.read_timeout", "no puede ser negativo")
+	v.check(s.ReadHeaderTimeout > 0, "server.read_header_timeout", "debe ser mayor a 0")
+	v.check(s.WriteTimeout >= 0, "server.write_timeout", "no puede ser negativo")
+	v.check(s.IdleTimeout >= 0, "server.idle_timeout", "no puede ser negativo")
+	v.check(s.ShutdownTimeout > 0, "server.shutdown_timeout", "debe ser mayor a 0")
+	v.check(s.MaxHeaderBytes > 0, "server.max_header_bytes", "debe ser mayor a 0")
+	v.check(s.MaxBodyBytes >= 0, "server.max_body_bytes", "no puede ser negativo")
+
+	db := cfg.Database
+	v.check(oneOf(db.Driver, "postgres", "sqlite"), "database.driver", "debe ser postgres o sqlite")
+	if strings.EqualFold(db.Driver, "sqlite") {
+		v.check(db.Path != "", "database.path", "es obligatorio con sqlite")
+		v.check(len(db.Replicas) == 0, "database.replicas", "no se admiten réplicas con sqlite")
+	} else {
+		v.check(db.Host != "", "database.host", "es obligatorio")
+		v.check(isPort(db.Port), "database.port", "debe ser un puerto entre 1 y 65535")
+		v.check(db.User != "", "database.user", "es obligatorio")
+		v.check(db.Name != "", "database.name", "es obligatorio")
+	}
+	v.check(oneOf(db.SSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full"), "database.ssl_mode",
+		"debe ser disable, allow, prefer, require, verify-ca o verify-full")
+	v.check((db.SSLCert == "") == (db.SSLKey == ""), "database.ssl_key", "el certificado y la clave del cliente deben indicarse juntos")
+	v.check(fileExists(db.SSLRootCert), "database.ssl_root_cert", "no se encontró el archivo %q", db.SSLRootCert)
+	v.check(fileExists(db.SSLCert), "database.ssl_cert", "no se encontró el archivo %q", db.SSLCert)
+	v.check(fileExists(db.SSLKey), "database.ssl_key", "no se encontró el archivo %q", db.SSLKey)
+	v.check(db.QueryTimeout >= 0, "database.query_timeout", "no puede ser negativo")
+	v.check(db.SlowQueryThreshold >= 0, "database.slow_query_threshold", "no puede ser negativo")
+	v.check(db.MaxOpenConns >= 0, "database.max_open_conns", "no puede ser negativo")
+	v.check(db.MaxIdleConns >= 0, "database.max_idle_conns", "no puede ser negativo")
+	v.check(db.MaxOpenConns == 0 || db.MaxIdleConns <= db.MaxOpenConns, "database.max_idle_conns",
+		"no puede superar a database.max_open_conns (%d)", db.MaxOpenConns)
+	v.check(db.ConnMaxLifetime >= 0, "database.conn_max_lifetime", "no puede ser negativo")
+	v.check(db.ConnMaxIdleTime >= 0, "database.conn_max_idle_time", "no puede ser negativo")
+	v.check(db.ConnectTimeout >= 0, "database.connect_timeout", "no puede ser negativo")
+	v.check(db.ConnectAttempts >= 1, "database.connect_attempts", "debe ser al menos 1")
+	v.check(db.ConnectBackoff > 0, "database.connect_backoff", "debe ser mayor a 0")
+	v.check(db.RetryAttempts >= 1, "database.retry_attempts", "debe ser al menos 1")
+	v.check(db.RetryBackoff > 0, "database.retry_backoff", "debe ser mayor a 0")
+	for _, replica := range db.Replicas {
+		host, port := splitReplica(replica, db.Port)
+		v.check(host != "" && isPort(port), "database.replicas", "réplica inválida %q, debe ser host o host:puerto", replica)
+	}
+	v.check(db.ReplicaHealthInterval > 0, "database.replica_health_interval", "debe ser mayor a 0")
+
+	v.check(cfg.Auth.JWTSecret != "", "auth.jwt_secret", "es obligatorio para firmar los tokens")
+	v.check(cfg.Auth.TokenTTL > 0, "auth.token_ttl", "debe ser mayor a 0")
+
+	for _, origin := range cfg.CORS.AllowedOrigins {
+		v.check(origin == "*" || strings.HasPrefix(origin, "http://") || strings.HasPrefix(origin, "https://"),
+			"cors.allowed_origins", "origen inválido %q, debe ser * o empezar con http:// o https://", origin)
+	}
+	v.check(cfg.CORS.MaxAge >= 0, "cors.max_age", "no puede ser negativo")
+
+	v.check(cfg.WebSocket.MaxClients >= 0, "websocket.max_clients", "no puede ser negativo")
+	v.check(cfg.WebSocket.MaxMessageBytes > 0, "websocket.max_message_bytes", "debe ser mayor a 0")
+	v.check(cfg.WebSocket.WriteTimeout > 0, "websocket.write_timeout", "debe ser mayor a 0")
+
+	v.check(oneOf(cfg.Log.Level, "debug", "info", "warn", "error"), "log.level", "debe ser debug, info, warn o error")
+	v.check(oneOf(cfg.Log.Format, "json", "text"), "log.format", "debe ser json o text")
+
+	v.check(oneOf(cfg.Tracing.Exporter, "none", "stdout", "otlp"), "tracing.exporter", "debe ser none, stdout u otlp")
+	v.check(cfg.Tracing.SampleRatio >= 0 && cfg.Tracing.SampleRatio <= 1, "tracing.sample_ratio", "debe estar entre 0 y 1")
+
+	v.check(cfg.History.KeepDays >= 0, "history.keep_days", "no puede ser negativo")
+	v.check(cfg.History.DeleteMonths >= 0, "history.delete_months", "no puede ser negativo")
+	// Un mes tiene al menos 28 dias: con keep_days mayor el tramo a reducir
+	// queda vacio y la reduccion no haria nada.
+	v.check(cfg.History.KeepDays == 0 || cfg.History.DeleteMonths == 0 || cfg.History.KeepDays < cfg.History.DeleteMonths*28,
+		"history.keep_days", "debe ser menor que history.delete_months (%d días)", cfg.History.DeleteMonths*28)
+
+	v.check(cfg.Jobs.Workers >= 0, "jobs.workers", "no puede ser negativo")
+	v.check(cfg.Jobs.PollInterval > 0, "jobs.poll_interval", "debe ser mayor a 0")
+
+	if cfg.Scheduler.Enabled {
+		v.check(cfg.Scheduler.LowStockSchedule != "", "scheduler.low_stock_schedule", "es obligatorio con el scheduler habilitado")
+		v.check(cfg.Scheduler.PriceChangesSchedule != "", "scheduler.price_changes_schedule", "es obligatorio con el scheduler habilitado")
+	}
+	v.check(cfg.Scheduler.LowStockThreshold >= 0, "scheduler.low_stock_threshold", "no puede ser negativo")
+
+	return errors.Join(v.errs...)
+}
+
+func isPort(value string) bool {
+	port, err := strconv.Atoi(value)
+	return err == nil && port > 0 && port <= 65535
+}
+
+// fileExists indica si path existe. Una ruta vacia se considera valida porque
+// el archivo es opcional.
+func fileExists(path string) bool {
+	if path == "" {
+		return true
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func oneOf(value string, options ...string) bool {
+	for _, option := range options {
+		if strings.EqualFold(value, option) {
+			return true
+		}
+	}
+	return false
+}
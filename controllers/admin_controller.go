@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"qisur-challenge/apperrors"
+	"qisur-challenge/scheduler"
+	"qisur-challenge/services"
+)
+
+type AdminController struct {
+	RetentionService services.HistoryRetentionService
+	Scheduler        *scheduler.Scheduler
+}
+
+func NewAdminController(retentionService services.HistoryRetentionService, sched *scheduler.Scheduler) *AdminController {
+	return &AdminController{RetentionService: retentionService, Scheduler: sched}
+}
+
+// GetHistoryRetention informa que eliminaria la politica de retencion si se
+// ejecutara ahora, junto con el resultado de la ultima ejecucion programada.
+func (ac *AdminController) GetHistoryRetention(w http.ResponseWriter, r *http.Request) {
+	preview, err := ac.RetentionService.Preview(r.Context())
+	if err != nil {
+		apperrors.Write(w, r, apperrors.Internal(err, "Error al calcular la depuración del historial"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"policy":   ac.RetentionService.Policy(),
+		"preview":  preview,
+		"last_run": ac.RetentionService.LastReport(),
+	})
+}
+
+// GetTasks devuelve el estado de las tareas programadas y si esta instancia es
+// la lider que las ejecuta.
+func (ac *AdminController) GetTasks(w http.ResponseWriter, r *http.Request) {
+	if ac.Scheduler == nil {
+		apperrors.Write(w, r, apperrors.New(apperrors.KindUnavailable, apperrors.CodeSchedulerDisabled, "El scheduler está deshabilitado en esta instancia"))
+		return
+	}
+
+	tasks, err := ac.Scheduler.Status(r.Context())
+	if err != nil {
+		apperrors.Write(w, r, apperrors.Internal(err, "Error al obtener las tareas programadas"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"instance":  ac.Scheduler.Instance(),
+		"is_leader": ac.Scheduler.IsLeader(),
+		"tasks":     tasks,
+	})
+}
@@ -3,11 +3,11 @@ package controllers
 import (
 	"encoding/json"
 	"net/http"
-	"time"
 
+	"qisur-challenge/apperrors"
 	"qisur-challenge/config"
+	"qisur-challenge/services"
 
-	"github.com/golang-jwt/jwt"
 	"gorm.io/gorm"
 )
 
@@ -17,26 +17,23 @@ type Credentials struct {
 }
 
 func Login(db *gorm.DB) http.HandlerFunc {
+	authService := services.NewAuthService(db)
 	return func(w http.ResponseWriter, r *http.Request) {
 		var creds Credentials
 		if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
-			http.Error(w, "Solicitud inválida", http.StatusBadRequest)
+			apperrors.Write(w, r, invalidBody(err))
 			return
 		}
 
-		if creds.Username != "admin" || creds.Password != "admin" {
-			http.Error(w, "Credenciales inválidas", http.StatusUnauthorized)
+		user, err := authService.Authenticate(r.Context(), creds.Username, creds.Password)
+		if err != nil {
+			apperrors.Write(w, r, apperrors.Internal(err, "Error al validar credenciales"))
 			return
 		}
 
-		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-			"username": creds.Username,
-			"exp":      time.Now().Add(1 * time.Hour).Unix(),
-		})
-
-		tokenString, err := token.SignedString([]byte(config.AppConfig.JWTSecret))
+		tokenString, err := services.GenerateToken(user.Username, user.Role, user.Language, config.AppConfig.Auth.TokenTTL)
 		if err != nil {
-			http.Error(w, "Error al generar token", http.StatusInternalServerError)
+			apperrors.Write(w, r, apperrors.Internal(err, "Error al generar token"))
 			return
 		}
 
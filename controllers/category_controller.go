@@ -4,8 +4,8 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
-	"strings"
 
+	"qisur-challenge/apperrors"
 	"qisur-challenge/models"
 	"qisur-challenge/services"
 	websocket "qisur-challenge/webSocket"
@@ -24,9 +24,9 @@ func NewCategoriesController(db *gorm.DB, categoriesService services.CategorySer
 }
 
 func (sc *CategoriesController) GetCategories(w http.ResponseWriter, r *http.Request) {
-	categories, err := sc.CategoriesService.GetAllCategories()
+	categories, err := sc.CategoriesService.GetAllCategories(r.Context())
 	if err != nil {
-		http.Error(w, "Error al obtener categorías", http.StatusInternalServerError)
+		apperrors.Write(w, r, apperrors.Internal(err, "Error al obtener categorías"))
 		return
 	}
 	categoryDTOs := sc.CategoriesService.ConvertToCategoryWithProductsDTOs(categories)
@@ -38,12 +38,12 @@ func (sc *CategoriesController) GetCategory(w http.ResponseWriter, r *http.Reque
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		http.Error(w, "ID inválido", http.StatusBadRequest)
+		apperrors.Write(w, r, invalidID())
 		return
 	}
-	category, err := sc.CategoriesService.GetCategoryByID(uint(id))
+	category, err := sc.CategoriesService.GetCategoryByID(r.Context(), uint(id))
 	if err != nil {
-		http.Error(w, "Categoría no encontrada", http.StatusNotFound)
+		apperrors.Write(w, r, apperrors.Internal(err, "Error al obtener la categoría"))
 		return
 	}
 	categoryDTO := sc.CategoriesService.ConvertToCategoryDTO(category)
@@ -54,19 +54,15 @@ func (sc *CategoriesController) GetCategory(w http.ResponseWriter, r *http.Reque
 func (sc *CategoriesController) CreateCategory(w http.ResponseWriter, r *http.Request) {
 	var category models.Category
 	if err := json.NewDecoder(r.Body).Decode(&category); err != nil {
-		http.Error(w, "Datos inválidos", http.StatusBadRequest)
+		apperrors.Write(w, r, invalidBody(err))
 		return
 	}
-	if err := sc.CategoriesService.CreateCategory(&category); err != nil {
-		if strings.Contains(err.Error(), "ya existe") {
-			http.Error(w, err.Error(), http.StatusConflict)
-		} else {
-			http.Error(w, "Error al crear la categoria", http.StatusInternalServerError)
-		}
+	if err := sc.CategoriesService.CreateCategory(r.Context(), &category); err != nil {
+		apperrors.Write(w, r, apperrors.Internal(err, "Error al crear la categoria"))
 		return
 	}
 
-	websocket.GetEventManager().BroadcastMessage(websocket.Message{
+	websocket.GetEventManager().BroadcastMessage(r.Context(), websocket.Message{
 		Type: "category_created",
 		Data: websocket.ProductData{
 			ID:   int(category.ID),
@@ -80,27 +76,23 @@ func (sc *CategoriesController) UpdateCategory(w http.ResponseWriter, r *http.Re
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		http.Error(w, "ID inválido", http.StatusBadRequest)
+		apperrors.Write(w, r, invalidID())
 		return
 	}
 
 	var category models.Category
 	if err := json.NewDecoder(r.Body).Decode(&category); err != nil {
-		http.Error(w, "Datos inválidos", http.StatusBadRequest)
+		apperrors.Write(w, r, invalidBody(err))
 		return
 	}
 	category.ID = uint(id)
-	if err := sc.CategoriesService.UpdateCategory(&category); err != nil {
-		if strings.Contains(err.Error(), "ya existe") {
-			http.Error(w, err.Error(), http.StatusConflict)
-		} else {
-			http.Error(w, "Error al actualizar la categoría", http.StatusInternalServerError)
-		}
+	if err := sc.CategoriesService.UpdateCategory(r.Context(), &category); err != nil {
+		apperrors.Write(w, r, apperrors.Internal(err, "Error al actualizar la categoría"))
 		return
 	}
 	categoryDTO := sc.CategoriesService.ConvertToCategoryDTO(&category)
 
-	websocket.GetEventManager().BroadcastMessage(websocket.Message{
+	websocket.GetEventManager().BroadcastMessage(r.Context(), websocket.Message{
 		Type: "category_updated",
 		Data: websocket.ProductData{
 			ID:   int(categoryDTO.ID),
@@ -114,22 +106,22 @@ func (sc *CategoriesController) DeleteCategory(w http.ResponseWriter, r *http.Re
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		http.Error(w, "ID inválido", http.StatusBadRequest)
+		apperrors.Write(w, r, invalidID())
 		return
 	}
-	category, err := sc.CategoriesService.GetCategoryByID(uint(id))
+	category, err := sc.CategoriesService.GetCategoryByID(r.Context(), uint(id))
 	if err != nil {
-		http.Error(w, "Categoría no encontrada", http.StatusNotFound)
+		apperrors.Write(w, r, apperrors.Internal(err, "Error al obtener la categoría"))
 		return
 	}
-	if err := sc.CategoriesService.DeleteCategory(category); err != nil {
-		http.Error(w, "Error al eliminar categoría", http.StatusInternalServerError)
+	if err := sc.CategoriesService.DeleteCategory(r.Context(), category); err != nil {
+		apperrors.Write(w, r, apperrors.Internal(err, "Error al eliminar categoría"))
 		return
 	}
-	websocket.GetEventManager().BroadcastMessage(websocket.Message{
+	websocket.GetEventManager().BroadcastMessage(r.Context(), websocket.Message{
 		Type: "category_deleted",
 		Data: websocket.ProductData{
-			ID:  int(category.ID),
+			ID:   int(category.ID),
 			Name: category.Name,
 		},
 	})
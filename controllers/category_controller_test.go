@@ -0,0 +1,150 @@
+package controllers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"qisur-challenge/apperrors"
+	"qisur-challenge/controllers"
+	"qisur-challenge/models"
+	"qisur-challenge/services"
+)
+
+func newCategoriesController(t *testing.T) *controllers.CategoriesController {
+	t.Helper()
+	service := services.NewCategoryService(newDB(t))
+	for _, name := range []string{"Gaming", "Oficina"} {
+		if err := service.CreateCategory(context.Background(), &models.Category{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return controllers.NewCategoriesController(nil, service)
+}
+
+func TestCategoriesControllerErrorMapping(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    func(cc *controllers.CategoriesController) http.HandlerFunc
+		method     string
+		id         string
+		body       string
+		wantStatus int
+		wantBody   string
+		wantCode   string
+	}{
+		{
+			name:       "obtener con ID invalido",
+			handler:    func(cc *controllers.CategoriesController) http.HandlerFunc { return cc.GetCategory },
+			method:     "GET",
+			id:         "x",
+			wantStatus: http.StatusBadRequest,
+			wantCode:   apperrors.CodeInvalidID,
+		},
+		{
+			name:       "obtener inexistente",
+			handler:    func(cc *controllers.CategoriesController) http.HandlerFunc { return cc.GetCategory },
+			method:     "GET",
+			id:         "999",
+			wantStatus: http.StatusNotFound,
+			wantCode:   apperrors.CodeCategoryNotFound,
+		},
+		{
+			name:       "crear con JSON invalido",
+			handler:    func(cc *controllers.CategoriesController) http.HandlerFunc { return cc.CreateCategory },
+			method:     "POST",
+			body:       "[",
+			wantStatus: http.StatusBadRequest,
+			wantCode:   apperrors.CodeInvalidBody,
+		},
+		{
+			name:       "crear con nombre repetido",
+			handler:    func(cc *controllers.CategoriesController) http.HandlerFunc { return cc.CreateCategory },
+			method:     "POST",
+			body:       `{"name":"Gaming"}`,
+			wantStatus: http.StatusConflict,
+			wantCode:   apperrors.CodeCategoryNameTaken,
+		},
+		{
+			name:       "crear",
+			handler:    func(cc *controllers.CategoriesController) http.HandlerFunc { return cc.CreateCategory },
+			method:     "POST",
+			body:       `{"name":"Audio"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `"name":"Audio"`,
+		},
+		{
+			name:       "renombrar con nombre repetido",
+			handler:    func(cc *controllers.CategoriesController) http.HandlerFunc { return cc.UpdateCategory },
+			method:     "PUT",
+			id:         "1",
+			body:       `{"name":"Oficina"}`,
+			wantStatus: http.StatusConflict,
+			wantCode:   apperrors.CodeCategoryNameTaken,
+		},
+		{
+			name:       "renombrar",
+			handler:    func(cc *controllers.CategoriesController) http.HandlerFunc { return cc.UpdateCategory },
+			method:     "PUT",
+			id:         "1",
+			body:       `{"name":"Juegos","description":"consolas"}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `"name":"Juegos"`,
+		},
+		{
+			name:       "eliminar inexistente",
+			handler:    func(cc *controllers.CategoriesController) http.HandlerFunc { return cc.DeleteCategory },
+			method:     "DELETE",
+			id:         "999",
+			wantStatus: http.StatusNotFound,
+			wantCode:   apperrors.CodeCategoryNotFound,
+		},
+		{
+			name:       "eliminar",
+			handler:    func(cc *controllers.CategoriesController) http.HandlerFunc { return cc.DeleteCategory },
+			method:     "DELETE",
+			id:         "2",
+			wantStatus: http.StatusNoContent,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cc := newCategoriesController(t)
+			target := "/api/categories"
+			if tt.id != "" {
+				target += "/" + tt.id
+			}
+
+			rec := serve(tt.handler(cc), tt.method, target, map[string]string{"id": tt.id}, tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, se esperaba %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantBody != "" && !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, se esperaba que contenga %s", rec.Body.String(), tt.wantBody)
+			}
+			if tt.wantCode != "" {
+				assertErrorBody(t, rec, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestCategoriesControllerUpdatePersists(t *testing.T) {
+	cc := newCategoriesController(t)
+
+	rec := serve(cc.UpdateCategory, "PUT", "/api/categories/1", map[string]string{"id": "1"}, `{"name":"Juegos","description":"consolas"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d (body: %s)", rec.Code, rec.Body.String())
+	}
+
+	rec = serve(cc.GetCategory, "GET", "/api/categories/1", map[string]string{"id": "1"}, "")
+	var dto models.CategoryWithProductsDTO
+	if err := json.NewDecoder(rec.Body).Decode(&dto); err != nil {
+		t.Fatal(err)
+	}
+	if dto.Name != "Juegos" || dto.Description != "consolas" {
+		t.Errorf("categoria guardada incorrecta: %+v", dto)
+	}
+}
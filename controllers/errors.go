@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"qisur-challenge/apperrors"
+)
+
+// invalidID es el error de un ID de la ruta que no es un numero.
+func invalidID() error {
+	return apperrors.Validation(apperrors.CodeInvalidID, "ID inválido").With("parameter", "id")
+}
+
+// invalidBody es el error de un body que no se pudo decodificar. Si el body
+// supero el tamaño maximo se informa como tal.
+func invalidBody(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return payloadTooLarge(err)
+	}
+	return apperrors.Validation(apperrors.CodeInvalidBody, "Datos inválidos").Wrap(err)
+}
+
+// invalidParameter es el error de un parametro de la query con un valor
+// invalido.
+func invalidParameter(name, format string, args ...any) *apperrors.Error {
+	return apperrors.Validation(apperrors.CodeInvalidParameter, format, args...).With("parameter", name)
+}
+
+// invalidDate es el error de un parametro de fecha que no tiene el formato
+// esperado.
+func invalidDate(name, layout string) error {
+	return invalidParameter(name, "Fecha '%s' inválida. Formato esperado: %s", name, layout).
+		With("format", layout).WithKey(apperrors.KeyParameterFormat)
+}
+
+// invalidChoice es el error de un parametro que no es ninguno de los valores
+// aceptados.
+func invalidChoice(name string, allowed ...string) error {
+	values := strings.Join(allowed, ", ")
+	return invalidParameter(name, "Parámetro '%s' inválido. Valores posibles: %s", name, values).
+		With("allowed", values).WithKey(apperrors.KeyParameterAllowed)
+}
+
+// invalidRange es el error de un rango de fechas que empieza despues de
+// terminar.
+func invalidRange() error {
+	return invalidParameter("start", "El parámetro 'start' no puede ser posterior a 'end'").WithKey(apperrors.KeyParameterRange)
+}
+
+func payloadTooLarge(err error) error {
+	return apperrors.New(apperrors.KindTooLarge, apperrors.CodePayloadTooLarge, "El archivo supera el tamaño máximo permitido").Wrap(err)
+}
+
+// NotFound responde las rutas que no existen.
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	apperrors.Write(w, r, apperrors.NotFound(apperrors.CodeRouteNotFound, "Ruta no encontrada").With("path", r.URL.Path))
+}
+
+// MethodNotAllowed responde los metodos que la ruta no acepta.
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	apperrors.Write(w, r, apperrors.New(apperrors.KindMethodNotAllowed, apperrors.CodeMethodNotAllowed, "Método no permitido").With("method", r.Method))
+}
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"strconv"
+
+	"qisur-challenge/apperrors"
+	"qisur-challenge/jobs"
+	"qisur-challenge/middlewares"
+	"qisur-challenge/models"
+
+	"github.com/gorilla/mux"
+)
+
+type JobController struct {
+	Jobs      jobs.Queue
+	ExportDir string
+}
+
+func NewJobController(jobQueue jobs.Queue, exportDir string) *JobController {
+	return &JobController{Jobs: jobQueue, ExportDir: exportDir}
+}
+
+func (jc *JobController) GetJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := jc.findJob(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job.ToDTO())
+}
+
+// GetJobResult descarga el archivo generado por un trabajo de exportacion.
+func (jc *JobController) GetJobResult(w http.ResponseWriter, r *http.Request) {
+	job, ok := jc.findJob(w, r)
+	if !ok {
+		return
+	}
+	if job.Type != jobs.TypeProductsExport {
+		apperrors.Write(w, r, apperrors.Validation(apperrors.CodeJobNotDownloadable, "El trabajo no genera un archivo descargable").With("type", job.Type))
+		return
+	}
+	if job.Status != models.JobStatusSucceeded {
+		apperrors.Write(w, r, apperrors.Conflict(apperrors.CodeJobNotFinished, "El trabajo todavía no finalizó correctamente").With("status", job.Status))
+		return
+	}
+
+	path, format, err := jobs.ExportFile(jc.ExportDir, job)
+	if err != nil {
+		apperrors.Write(w, r, apperrors.Internal(err, "Error al obtener el resultado del trabajo"))
+		return
+	}
+	// El archivo queda en el disco del servidor que ejecuto el trabajo; sin un
+	// JOB_EXPORT_DIR compartido otra replica no lo tiene.
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		apperrors.Write(w, r, apperrors.NotFound(apperrors.CodeJobResultMissing, "El archivo del trabajo no está disponible en este servidor"))
+		return
+	} else if err != nil {
+		apperrors.Write(w, r, apperrors.Internal(err, "Error al obtener el resultado del trabajo"))
+		return
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="productos.%s"`, format))
+	http.ServeFile(w, r, path)
+}
+
+func (jc *JobController) findJob(w http.ResponseWriter, r *http.Request) (*models.Job, bool) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apperrors.Write(w, r, invalidID())
+		return nil, false
+	}
+	job, err := jc.Jobs.Get(r.Context(), uint(id))
+	if err != nil {
+		apperrors.Write(w, r, apperrors.Internal(err, "Error al obtener el trabajo"))
+		return nil, false
+	}
+	// Un trabajo solo lo ven quien lo encolo y los administradores.
+	ctx := r.Context()
+	if job.CreatedBy != middlewares.UsernameFromContext(ctx) && middlewares.RoleFromContext(ctx) != models.RoleAdmin {
+		apperrors.Write(w, r, apperrors.Forbidden(apperrors.CodeForbidden, "No tiene permiso para realizar esta acción"))
+		return nil, false
+	}
+	return job, true
+}
@@ -3,12 +3,18 @@ package controllers
 import (
 	"encoding/json"
 	"errors"
-	"log"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"qisur-challenge/apperrors"
+	"qisur-challenge/i18n"
+	"qisur-challenge/jobs"
+	"qisur-challenge/logger"
+	"qisur-challenge/middlewares"
 	"qisur-challenge/models"
 	"qisur-challenge/services"
 	websocket "qisur-challenge/webSocket"
@@ -19,17 +25,18 @@ import (
 
 type ProductController struct {
 	ProductService services.ProductService
+	Jobs           jobs.Queue
 	DB             *gorm.DB
 }
 
-func NewProductController(db *gorm.DB, productService services.ProductService) *ProductController {
-	return &ProductController{DB: db, ProductService: productService}
+func NewProductController(db *gorm.DB, productService services.ProductService, jobQueue jobs.Queue) *ProductController {
+	return &ProductController{DB: db, ProductService: productService, Jobs: jobQueue}
 }
 
 func (pc *ProductController) GetProducts(w http.ResponseWriter, r *http.Request) {
-	products, err := pc.ProductService.GetAllProducts()
+	products, err := pc.ProductService.GetAllProducts(r.Context())
 	if err != nil {
-		http.Error(w, "Error al obtener productos", http.StatusInternalServerError)
+		apperrors.Write(w, r, apperrors.Internal(err, "Error al obtener productos"))
 		return
 	}
 	productDtos := pc.ProductService.ConvertToProductDTOs(products)
@@ -41,13 +48,23 @@ func (pc *ProductController) GetProduct(w http.ResponseWriter, r *http.Request)
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		http.Error(w, "ID inválido", http.StatusBadRequest)
+		apperrors.Write(w, r, invalidID())
 		return
 	}
 
-	product, err := pc.ProductService.GetProductByID(uint(id))
+	var product *models.Product
+	if asOfStr := r.URL.Query().Get("as_of"); asOfStr != "" {
+		asOf, parseErr := time.Parse(time.RFC3339, asOfStr)
+		if parseErr != nil {
+			apperrors.Write(w, r, invalidDate("as_of", "RFC3339"))
+			return
+		}
+		product, err = pc.ProductService.GetProductAsOf(r.Context(), uint(id), asOf)
+	} else {
+		product, err = pc.ProductService.GetProductByID(r.Context(), uint(id))
+	}
 	if err != nil {
-		http.Error(w, "Producto no encontrado", http.StatusNotFound)
+		apperrors.Write(w, r, apperrors.Internal(err, "Error al obtener producto"))
 		return
 	}
 
@@ -58,18 +75,14 @@ func (pc *ProductController) GetProduct(w http.ResponseWriter, r *http.Request)
 func (pc *ProductController) CreateProduct(w http.ResponseWriter, r *http.Request) {
 	var product models.Product
 	if err := json.NewDecoder(r.Body).Decode(&product); err != nil {
-		http.Error(w, "Datos inválidos", http.StatusBadRequest)
+		apperrors.Write(w, r, invalidBody(err))
 		return
 	}
-	if err := pc.ProductService.CreateProduct(&product); err != nil {
-		if strings.Contains(err.Error(), "ya existe") {
-			http.Error(w, err.Error(), http.StatusConflict)
-		} else {
-			http.Error(w, "Error al crear producto", http.StatusInternalServerError)
-		}
+	if err := pc.ProductService.CreateProduct(r.Context(), &product); err != nil {
+		apperrors.Write(w, r, apperrors.Internal(err, "Error al crear producto"))
 		return
 	}
-	websocket.GetEventManager().BroadcastMessage(websocket.Message{
+	websocket.GetEventManager().BroadcastMessage(r.Context(), websocket.Message{
 		Type: "product_created",
 		Data: websocket.ProductData{
 			ID:   int(product.ID),
@@ -84,34 +97,32 @@ func (pc *ProductController) UpdateProduct(w http.ResponseWriter, r *http.Reques
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		http.Error(w, "ID inválido", http.StatusBadRequest)
+		apperrors.Write(w, r, invalidID())
 		return
 	}
 
 	var req models.UpdateProductRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Datos inválidos", http.StatusBadRequest)
+		apperrors.Write(w, r, invalidBody(err))
 		return
 	}
 
-	updatedProduct, err := pc.ProductService.UpdateProduct(uint(id), &req)
+	actor := middlewares.UsernameFromContext(r.Context())
+	updatedProduct, changed, err := pc.ProductService.UpdateProduct(r.Context(), uint(id), &req, actor)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			log.Printf("UpdateProduct: Producto no encontrado ID=%d", id)
-			http.Error(w, "Producto no encontrado", http.StatusNotFound)
-		} else {
-			log.Printf("UpdateProduct: Error actualizando producto ID=%d, error=%v", id, err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-		}
+		apperrors.Write(w, r, apperrors.Internal(err, "Error al actualizar producto"))
 		return
 	}
-	websocket.GetEventManager().BroadcastMessage(websocket.Message{
-		Type: "product_upgraded",
-		Data: websocket.ProductData{
-			ID:   int(updatedProduct.ID),
-			Name: updatedProduct.Name,
-		},
-	})
+	w.Header().Set("X-Product-Changed", strconv.FormatBool(changed))
+	if changed {
+		websocket.GetEventManager().BroadcastMessage(r.Context(), websocket.Message{
+			Type: "product_upgraded",
+			Data: websocket.ProductData{
+				ID:   int(updatedProduct.ID),
+				Name: updatedProduct.Name,
+			},
+		})
+	}
 	json.NewEncoder(w).Encode(pc.ProductService.ConvertToProductDTO(updatedProduct))
 }
 
@@ -119,19 +130,19 @@ func (pc *ProductController) DeleteProduct(w http.ResponseWriter, r *http.Reques
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		http.Error(w, "ID inválido", http.StatusBadRequest)
+		apperrors.Write(w, r, invalidID())
 		return
 	}
-	product, err := pc.ProductService.GetProductByID(uint(id))
+	product, err := pc.ProductService.GetProductByID(r.Context(), uint(id))
 	if err != nil {
-		http.Error(w, "Producto no encontrado", http.StatusNotFound)
+		apperrors.Write(w, r, apperrors.Internal(err, "Error al obtener producto"))
 		return
 	}
-	if err := pc.ProductService.DeleteProduct(product); err != nil {
-		http.Error(w, "Error al eliminar producto", http.StatusInternalServerError)
+	if err := pc.ProductService.DeleteProduct(r.Context(), product); err != nil {
+		apperrors.Write(w, r, apperrors.Internal(err, "Error al eliminar producto"))
 		return
 	}
-	websocket.GetEventManager().BroadcastMessage(websocket.Message{
+	websocket.GetEventManager().BroadcastMessage(r.Context(), websocket.Message{
 		Type: "product_delete",
 		Data: websocket.ProductData{
 			ID:   int(product.ID),
@@ -142,52 +153,326 @@ func (pc *ProductController) DeleteProduct(w http.ResponseWriter, r *http.Reques
 
 }
 
-func (pc *ProductController) GetProductHistory(w http.ResponseWriter, r *http.Request) {
+func (pc *ProductController) RevertProduct(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		http.Error(w, "ID inválido", http.StatusBadRequest)
+		apperrors.Write(w, r, invalidID())
+		return
+	}
+	historyID, err := strconv.Atoi(r.URL.Query().Get("history_id"))
+	if err != nil || historyID <= 0 {
+		apperrors.Write(w, r, invalidParameter("history_id", "Parámetro 'history_id' inválido"))
 		return
 	}
 
-	startStr := r.URL.Query().Get("start")
-	endStr := r.URL.Query().Get("end")
+	actor := middlewares.UsernameFromContext(r.Context())
+	product, changed, err := pc.ProductService.RevertProduct(r.Context(), uint(id), uint(historyID), actor)
+	if err != nil {
+		apperrors.Write(w, r, apperrors.Internal(err, "Error al revertir producto"))
+		return
+	}
 
-	var startTime, endTime *time.Time
-	const layout = "2006-01-02"
+	w.Header().Set("X-Product-Changed", strconv.FormatBool(changed))
+	if changed {
+		websocket.GetEventManager().BroadcastMessage(r.Context(), websocket.Message{
+			Type: "product_upgraded",
+			Data: websocket.ProductData{
+				ID:   int(product.ID),
+				Name: product.Name,
+			},
+		})
+	}
+	json.NewEncoder(w).Encode(pc.ProductService.ConvertToProductDTO(product))
+}
 
-	if startStr != "" {
-		t, err := time.Parse(layout, startStr)
+// SchedulePriceChange programa un cambio de precio que el scheduler aplica
+// cuando llega su fecha de vigencia.
+func (pc *ProductController) SchedulePriceChange(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apperrors.Write(w, r, invalidID())
+		return
+	}
+
+	var req models.SchedulePriceChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apperrors.Write(w, r, invalidBody(err))
+		return
+	}
+	actor := middlewares.UsernameFromContext(r.Context())
+	change, err := pc.ProductService.SchedulePriceChange(r.Context(), uint(id), &req, actor)
+	if err != nil {
+		apperrors.Write(w, r, apperrors.Internal(err, "Error al programar el cambio de precio"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(change)
+}
+
+func (pc *ProductController) GetScheduledPriceChanges(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apperrors.Write(w, r, invalidID())
+		return
+	}
+
+	changes, err := pc.ProductService.GetScheduledPriceChanges(r.Context(), uint(id))
+	if err != nil {
+		apperrors.Write(w, r, apperrors.Internal(err, "Error al obtener los cambios de precio"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(changes)
+}
+
+// maxImportSize limita el tamaño del archivo aceptado por ImportProducts.
+const maxImportSize = 32 << 20
+
+func (pc *ProductController) ImportProducts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	format := query.Get("format")
+	if format == "" {
+		switch {
+		case strings.HasPrefix(r.Header.Get("Content-Type"), "text/csv"):
+			format = "csv"
+		case strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-ndjson"):
+			format = "ndjson"
+		}
+	}
+	if !services.IsValidImportFormat(format) {
+		apperrors.Write(w, r, invalidChoice("format", "csv", "ndjson"))
+		return
+	}
+
+	mode := query.Get("mode")
+	if mode == "" {
+		mode = "dry_run"
+	}
+	if mode != "dry_run" && mode != "commit" {
+		apperrors.Write(w, r, invalidChoice("mode", "dry_run", "commit"))
+		return
+	}
+
+	mapping := map[string]string{}
+	if mapStr := query.Get("map"); mapStr != "" {
+		for _, pair := range strings.Split(mapStr, ",") {
+			column, field, ok := strings.Cut(pair, ":")
+			if !ok {
+				apperrors.Write(w, r, invalidParameter("map", "Parámetro 'map' inválido. Formato esperado: columna:campo,..."))
+				return
+			}
+			mapping[strings.TrimSpace(column)] = strings.TrimSpace(field)
+		}
+	}
+
+	// El archivo puede tardar mas que HTTP_READ_TIMEOUT en subir; el limite lo
+	// impone maxImportSize.
+	http.NewResponseController(w).SetReadDeadline(time.Time{})
+	body := http.MaxBytesReader(w, r.Body, maxImportSize)
+	actor := middlewares.UsernameFromContext(r.Context())
+
+	if async, _ := strconv.ParseBool(query.Get("async")); async {
+		data, err := io.ReadAll(body)
 		if err != nil {
-			http.Error(w, "Fecha 'start' inválida. Formato esperado: YYYY-MM-DD", http.StatusBadRequest)
+			apperrors.Write(w, r, payloadTooLarge(err))
 			return
 		}
-		startTime = &t
+		pc.enqueueJob(w, r, jobs.TypeProductsImport, jobs.ImportPayload{
+			Format:  format,
+			DryRun:  mode == "dry_run",
+			Actor:   actor,
+			Mapping: mapping,
+			Data:    string(data),
+			Lang:    i18n.FromContext(r.Context()),
+		})
+		return
+	}
+
+	report, err := pc.ProductService.ImportProducts(r.Context(), body, services.ImportOptions{
+		Format:  format,
+		DryRun:  mode == "dry_run",
+		Actor:   actor,
+		Mapping: mapping,
+	})
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			err = payloadTooLarge(err)
+		}
+		apperrors.Write(w, r, apperrors.Internal(err, "Error al importar productos"))
+		return
 	}
 
-	if endStr != "" {
+	if !report.DryRun && report.Created+report.Updated > 0 {
+		websocket.GetEventManager().BroadcastMessage(r.Context(), websocket.Message{
+			Type: "products_imported",
+			Data: websocket.ProductData{
+				Name: fmt.Sprintf("%d creados, %d actualizados", report.Created, report.Updated),
+			},
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+func (pc *ProductController) ExportProducts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	format := query.Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	contentType := services.ExportContentType(format)
+	if contentType == "" {
+		apperrors.Write(w, r, invalidChoice("format", "csv", "ndjson", "xlsx"))
+		return
+	}
+
+	var columns []string
+	if columnsStr := query.Get("columns"); columnsStr != "" {
+		for _, c := range strings.Split(columnsStr, ",") {
+			columns = append(columns, strings.TrimSpace(c))
+		}
+		if err := services.ValidateExportColumns(columns); err != nil {
+			apperrors.Write(w, r, err)
+			return
+		}
+	}
+
+	if async, _ := strconv.ParseBool(query.Get("async")); async {
+		pc.enqueueJob(w, r, jobs.TypeProductsExport, jobs.ExportPayload{
+			Format:  format,
+			Columns: columns,
+			Name:    query.Get("name"),
+			Sort:    query.Get("sort"),
+		})
+		return
+	}
+
+	// La exportacion se escribe a medida que se lee de la base, por lo que no
+	// se le aplica HTTP_WRITE_TIMEOUT.
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="productos.%s"`, format))
+
+	err := pc.ProductService.ExportProducts(r.Context(), w, services.ExportOptions{
+		Format:  format,
+		Columns: columns,
+		Name:    query.Get("name"),
+		Sort:    query.Get("sort"),
+	})
+	if err != nil {
+		// La respuesta ya pudo haber comenzado, por lo que solo se registra el error.
+		logger.FromContext(r.Context()).Error("Error exportando productos", "format", format, logger.Err(err))
+	}
+}
+
+// enqueueJob encola el trabajo y responde 202 con su estado inicial.
+func (pc *ProductController) enqueueJob(w http.ResponseWriter, r *http.Request, jobType string, payload interface{}) {
+	job, err := pc.Jobs.Enqueue(r.Context(), jobType, payload, 3, middlewares.UsernameFromContext(r.Context()))
+	if err != nil {
+		apperrors.Write(w, r, apperrors.Internal(err, "Error al encolar el trabajo"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("/api/jobs/%d", job.ID))
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job.ToDTO())
+}
+
+// parseDateRange lee los parametros start y end (YYYY-MM-DD). end incluye el
+// dia completo, asi que se devuelve como el inicio del dia siguiente y se usa
+// como limite exclusivo.
+func parseDateRange(r *http.Request) (start, end *time.Time, err error) {
+	const layout = "2006-01-02"
+	query := r.URL.Query()
+
+	if startStr := query.Get("start"); startStr != "" {
+		t, err := time.Parse(layout, startStr)
+		if err != nil {
+			return nil, nil, invalidDate("start", "YYYY-MM-DD")
+		}
+		start = &t
+	}
+	if endStr := query.Get("end"); endStr != "" {
 		t, err := time.Parse(layout, endStr)
 		if err != nil {
-			http.Error(w, "Fecha 'end' inválida. Formato esperado: YYYY-MM-DD", http.StatusBadRequest)
-			return
+			return nil, nil, invalidDate("end", "YYYY-MM-DD")
 		}
-		endTime = &t
+		t = t.AddDate(0, 0, 1)
+		end = &t
+	}
+	if start != nil && end != nil && !start.Before(*end) {
+		return nil, nil, invalidRange()
 	}
+	return start, end, nil
+}
 
-	if startTime != nil && endTime != nil && startTime.After(*endTime) {
-		http.Error(w, "El parámetro 'start' no puede ser posterior a 'end'", http.StatusBadRequest)
+func (pc *ProductController) GetProductHistory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apperrors.Write(w, r, invalidID())
 		return
 	}
 
-	history, err := pc.ProductService.GetProductHistory(uint(id), startTime, endTime)
+	startTime, endTime, err := parseDateRange(r)
 	if err != nil {
-		http.Error(w, "Error al obtener historial", http.StatusInternalServerError)
+		apperrors.Write(w, r, err)
+		return
+	}
+
+	history, err := pc.ProductService.GetProductHistory(r.Context(), uint(id), startTime, endTime)
+	if err != nil {
+		apperrors.Write(w, r, apperrors.Internal(err, "Error al obtener historial"))
 		return
 	}
 
 	json.NewEncoder(w).Encode(history)
 }
 
+func (pc *ProductController) GetProductHistoryStats(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apperrors.Write(w, r, invalidID())
+		return
+	}
+
+	query := r.URL.Query()
+	interval := query.Get("interval")
+	if interval == "" {
+		interval = "day"
+	}
+	if !services.IsValidHistoryInterval(interval) {
+		apperrors.Write(w, r, invalidChoice("interval", "hour", "day", "week", "month"))
+		return
+	}
+
+	startTime, endTime, err := parseDateRange(r)
+	if err != nil {
+		apperrors.Write(w, r, err)
+		return
+	}
+
+	fill, _ := strconv.ParseBool(query.Get("fill"))
+
+	stats, err := pc.ProductService.GetProductHistoryStats(r.Context(), uint(id), interval, startTime, endTime, fill)
+	if err != nil {
+		apperrors.Write(w, r, apperrors.Internal(err, "Error al obtener estadísticas del historial"))
+		return
+	}
+
+	json.NewEncoder(w).Encode(stats)
+}
+
 func (pc *ProductController) SearchHandler(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
 	searchType := query.Get("type")
@@ -207,20 +492,20 @@ func (pc *ProductController) SearchHandler(w http.ResponseWriter, r *http.Reques
 
 	switch searchType {
 	case "product":
-		results, err := pc.ProductService.SearchProducts(name, sort, page, limit)
+		results, err := pc.ProductService.SearchProducts(r.Context(), name, sort, page, limit)
 		if err != nil {
-			http.Error(w, "Error al buscar productos", http.StatusInternalServerError)
+			apperrors.Write(w, r, apperrors.Internal(err, "Error al buscar productos"))
 			return
 		}
 		json.NewEncoder(w).Encode(results)
 	case "category":
-		results, err := pc.ProductService.SearchCategories(name, sort, page, limit)
+		results, err := pc.ProductService.SearchCategories(r.Context(), name, sort, page, limit)
 		if err != nil {
-			http.Error(w, "Error al buscar categorías", http.StatusInternalServerError)
+			apperrors.Write(w, r, apperrors.Internal(err, "Error al buscar categorías"))
 			return
 		}
 		json.NewEncoder(w).Encode(results)
 	default:
-		http.Error(w, "Tipo de búsqueda inválido", http.StatusBadRequest)
+		apperrors.Write(w, r, invalidChoice("type", "product", "category"))
 	}
 }
@@ -0,0 +1,342 @@
+package controllers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"qisur-challenge/apperrors"
+	"qisur-challenge/config"
+	"qisur-challenge/controllers"
+	"qisur-challenge/migrations"
+	"qisur-challenge/models"
+	"qisur-challenge/services"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// newDB abre una base SQLite en memoria con las migraciones aplicadas.
+func newDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	cfg := config.Default()
+	cfg.Database.Driver = "sqlite"
+	cfg.Database.Path = ":memory:"
+	config.AppConfig = cfg
+
+	db, err := config.CONNECTDB(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// serve ejecuta handler con las variables de ruta indicadas, como lo haria el
+// router.
+func serve(handler http.HandlerFunc, method, target string, vars map[string]string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req = mux.SetURLVars(req, vars)
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+// assertErrorBody verifica que la respuesta tenga el cuerpo de error comun con
+// el codigo indicado.
+func assertErrorBody(t *testing.T, rec *httptest.ResponseRecorder, code string) {
+	t.Helper()
+	var body apperrors.Response
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("el cuerpo de error no es JSON: %v (body: %s)", err, rec.Body.String())
+	}
+	if body.Code != code || body.Message == "" {
+		t.Errorf("error = %+v, se esperaba el codigo %q con mensaje", body, code)
+	}
+}
+
+func newProductController(t *testing.T) (*controllers.ProductController, models.Product) {
+	t.Helper()
+	service := services.NewProductService(newDB(t))
+	for _, name := range []string{"Teclado", "Mouse"} {
+		product := models.Product{Name: name, Price: 10, Stock: 5}
+		if err := service.CreateProduct(context.Background(), &product); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mouse, err := service.GetProductByID(context.Background(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return controllers.NewProductController(nil, service, nil), *mouse
+}
+
+func TestProductControllerErrorMapping(t *testing.T) {
+	type request struct {
+		method string
+		target string
+		id     string
+		body   string
+	}
+	tests := []struct {
+		name        string
+		req         func(mouse models.Product) request
+		handler     func(pc *controllers.ProductController) http.HandlerFunc
+		wantStatus  int
+		wantBody    string
+		wantCode    string
+		wantChanged string
+	}{
+		{
+			name:       "obtener con ID invalido",
+			req:        func(models.Product) request { return request{"GET", "/api/products/x", "x", ""} },
+			handler:    func(pc *controllers.ProductController) http.HandlerFunc { return pc.GetProduct },
+			wantStatus: http.StatusBadRequest,
+			wantCode:   apperrors.CodeInvalidID,
+		},
+		{
+			name:       "obtener inexistente",
+			req:        func(models.Product) request { return request{"GET", "/api/products/999", "999", ""} },
+			handler:    func(pc *controllers.ProductController) http.HandlerFunc { return pc.GetProduct },
+			wantStatus: http.StatusNotFound,
+			wantCode:   apperrors.CodeProductNotFound,
+		},
+		{
+			name:       "obtener existente",
+			req:        func(models.Product) request { return request{"GET", "/api/products/2", "2", ""} },
+			handler:    func(pc *controllers.ProductController) http.HandlerFunc { return pc.GetProduct },
+			wantStatus: http.StatusOK,
+			wantBody:   `"name":"Mouse"`,
+		},
+		{
+			name:       "obtener con as_of invalido",
+			req:        func(models.Product) request { return request{"GET", "/api/products/2?as_of=ayer", "2", ""} },
+			handler:    func(pc *controllers.ProductController) http.HandlerFunc { return pc.GetProduct },
+			wantStatus: http.StatusBadRequest,
+			wantCode:   apperrors.CodeInvalidParameter,
+		},
+		{
+			name: "obtener con as_of anterior a la creacion",
+			req: func(m models.Product) request {
+				asOf := url.QueryEscape(m.CreatedAt.Add(-time.Hour).Format(time.RFC3339))
+				return request{"GET", "/api/products/2?as_of=" + asOf, "2", ""}
+			},
+			handler:    func(pc *controllers.ProductController) http.HandlerFunc { return pc.GetProduct },
+			wantStatus: http.StatusNotFound,
+			wantCode:   apperrors.CodeProductNotFound,
+		},
+		{
+			name:       "crear con JSON invalido",
+			req:        func(models.Product) request { return request{"POST", "/api/products", "", "{"} },
+			handler:    func(pc *controllers.ProductController) http.HandlerFunc { return pc.CreateProduct },
+			wantStatus: http.StatusBadRequest,
+			wantCode:   apperrors.CodeInvalidBody,
+		},
+		{
+			name:       "crear con nombre repetido",
+			req:        func(models.Product) request { return request{"POST", "/api/products", "", `{"name":"Mouse"}`} },
+			handler:    func(pc *controllers.ProductController) http.HandlerFunc { return pc.CreateProduct },
+			wantStatus: http.StatusConflict,
+			wantCode:   apperrors.CodeProductNameTaken,
+			wantBody:   "ya existe",
+		},
+		{
+			name: "crear",
+			req: func(models.Product) request {
+				return request{"POST", "/api/products", "", `{"name":"Monitor","price":100}`}
+			},
+			handler:    func(pc *controllers.ProductController) http.HandlerFunc { return pc.CreateProduct },
+			wantStatus: http.StatusOK,
+			wantBody:   `"name":"Monitor"`,
+		},
+		{
+			name:       "actualizar con ID invalido",
+			req:        func(models.Product) request { return request{"PUT", "/api/products/x", "x", `{"price":1}`} },
+			handler:    func(pc *controllers.ProductController) http.HandlerFunc { return pc.UpdateProduct },
+			wantStatus: http.StatusBadRequest,
+			wantCode:   apperrors.CodeInvalidID,
+		},
+		{
+			name:       "actualizar con JSON invalido",
+			req:        func(models.Product) request { return request{"PUT", "/api/products/2", "2", `{"price":"caro"}`} },
+			handler:    func(pc *controllers.ProductController) http.HandlerFunc { return pc.UpdateProduct },
+			wantStatus: http.StatusBadRequest,
+			wantCode:   apperrors.CodeInvalidBody,
+		},
+		{
+			name:       "actualizar inexistente",
+			req:        func(models.Product) request { return request{"PUT", "/api/products/999", "999", `{"price":1}`} },
+			handler:    func(pc *controllers.ProductController) http.HandlerFunc { return pc.UpdateProduct },
+			wantStatus: http.StatusNotFound,
+			wantCode:   apperrors.CodeProductNotFound,
+		},
+		{
+			name:       "actualizar con nombre repetido",
+			req:        func(models.Product) request { return request{"PUT", "/api/products/2", "2", `{"name":"Teclado"}`} },
+			handler:    func(pc *controllers.ProductController) http.HandlerFunc { return pc.UpdateProduct },
+			wantStatus: http.StatusConflict,
+			wantCode:   apperrors.CodeProductNameTaken,
+			wantBody:   "ya existe",
+		},
+		{
+			name:        "actualizar parcialmente",
+			req:         func(models.Product) request { return request{"PUT", "/api/products/2", "2", `{"stock":1}`} },
+			handler:     func(pc *controllers.ProductController) http.HandlerFunc { return pc.UpdateProduct },
+			wantStatus:  http.StatusOK,
+			wantBody:    `"stock":1`,
+			wantChanged: "true",
+		},
+		{
+			name:        "actualizar sin cambios",
+			req:         func(models.Product) request { return request{"PUT", "/api/products/2", "2", `{"price":10}`} },
+			handler:     func(pc *controllers.ProductController) http.HandlerFunc { return pc.UpdateProduct },
+			wantStatus:  http.StatusOK,
+			wantChanged: "false",
+		},
+		{
+			name:       "revertir sin history_id",
+			req:        func(models.Product) request { return request{"POST", "/api/products/2/revert", "2", ""} },
+			handler:    func(pc *controllers.ProductController) http.HandlerFunc { return pc.RevertProduct },
+			wantStatus: http.StatusBadRequest,
+			wantCode:   apperrors.CodeInvalidParameter,
+		},
+		{
+			name:       "revertir historial inexistente",
+			req:        func(models.Product) request { return request{"POST", "/api/products/2/revert?history_id=999", "2", ""} },
+			handler:    func(pc *controllers.ProductController) http.HandlerFunc { return pc.RevertProduct },
+			wantStatus: http.StatusNotFound,
+			wantCode:   apperrors.CodeHistoryNotFound,
+		},
+		{
+			name:       "eliminar inexistente",
+			req:        func(models.Product) request { return request{"DELETE", "/api/products/999", "999", ""} },
+			handler:    func(pc *controllers.ProductController) http.HandlerFunc { return pc.DeleteProduct },
+			wantStatus: http.StatusNotFound,
+			wantCode:   apperrors.CodeProductNotFound,
+		},
+		{
+			name:       "eliminar",
+			req:        func(models.Product) request { return request{"DELETE", "/api/products/2", "2", ""} },
+			handler:    func(pc *controllers.ProductController) http.HandlerFunc { return pc.DeleteProduct },
+			wantStatus: http.StatusNoContent,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pc, mouse := newProductController(t)
+			req := tt.req(mouse)
+
+			rec := serve(tt.handler(pc), req.method, req.target, map[string]string{"id": req.id}, req.body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, se esperaba %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantBody != "" && !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, se esperaba que contenga %s", rec.Body.String(), tt.wantBody)
+			}
+			if tt.wantCode != "" {
+				assertErrorBody(t, rec, tt.wantCode)
+			}
+			if got := rec.Header().Get("X-Product-Changed"); got != tt.wantChanged {
+				t.Errorf("X-Product-Changed = %q, se esperaba %q", got, tt.wantChanged)
+			}
+		})
+	}
+}
+
+func TestProductControllerUpdateRecordsHistory(t *testing.T) {
+	pc, mouse := newProductController(t)
+	vars := map[string]string{"id": "2"}
+
+	rec := serve(pc.UpdateProduct, "PUT", "/api/products/2", vars, `{"price":12.5,"reason":"inflacion"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d (body: %s)", rec.Code, rec.Body.String())
+	}
+
+	history, err := pc.ProductService.GetProductHistory(context.Background(), mouse.ID, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].OldPrice != 10 || history[0].NewPrice != 12.5 || history[0].Reason != "inflacion" {
+		t.Fatalf("historial incorrecto: %+v", history)
+	}
+
+	rec = serve(pc.GetProduct, "GET", "/api/products/2?as_of="+url.QueryEscape(mouse.CreatedAt.Format(time.RFC3339Nano)), vars, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d (body: %s)", rec.Code, rec.Body.String())
+	}
+	var dto models.ProductDTO
+	if err := json.NewDecoder(rec.Body).Decode(&dto); err != nil {
+		t.Fatal(err)
+	}
+	if dto.Price != 10 {
+		t.Errorf("precio a la fecha de creacion = %v, se esperaba 10", dto.Price)
+	}
+}
+
+// missingAsOfService simula un producto que no existia en la fecha pedida.
+// El resto de los metodos no se implementan: usarlos hace fallar el test.
+type missingAsOfService struct {
+	services.ProductService
+}
+
+func (missingAsOfService) GetProductAsOf(ctx context.Context, id uint, asOf time.Time) (*models.Product, error) {
+	return nil, apperrors.NotFound(apperrors.CodeProductNotFound, "El producto no existía en la fecha indicada")
+}
+
+func TestGetProductAsOfBeforeCreation(t *testing.T) {
+	pc := controllers.NewProductController(nil, missingAsOfService{}, nil)
+	rec := serve(pc.GetProduct, "GET", "/api/products/1?as_of=2020-01-01T00:00:00Z", map[string]string{"id": "1"}, "")
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, se esperaba %d (body: %s)", rec.Code, http.StatusNotFound, rec.Body.String())
+	}
+}
+
+func TestProductControllerHistoryIncludesEndDay(t *testing.T) {
+	pc, _ := newProductController(t)
+	vars := map[string]string{"id": "2"}
+	for _, body := range []string{`{"price":11}`, `{"price":12}`} {
+		if rec := serve(pc.UpdateProduct, "PUT", "/api/products/2", vars, body); rec.Code != http.StatusOK {
+			t.Fatalf("status = %d (body: %s)", rec.Code, rec.Body.String())
+		}
+	}
+	today := time.Now().UTC().Format("2006-01-02")
+	query := "?start=" + today + "&end=" + today
+
+	rec := serve(pc.GetProductHistory, "GET", "/api/products/2/history"+query, vars, "")
+	var history []models.ProductHistory
+	if err := json.NewDecoder(rec.Body).Decode(&history); err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 {
+		t.Errorf("historial del dia = %d entradas, se esperaban 2", len(history))
+	}
+
+	rec = serve(pc.GetProductHistoryStats, "GET", "/api/products/2/history/stats"+query, vars, "")
+	var stats models.HistoryStatsDTO
+	if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+	if len(stats.Buckets) != 1 || stats.Buckets[0].Changes != 2 || stats.Buckets[0].ClosePrice != 12 {
+		t.Fatalf("buckets = %+v, se esperaba un intervalo con los 2 cambios", stats.Buckets)
+	}
+	if want := stats.Buckets[0].Start.AddDate(0, 0, 1); !stats.End.Equal(want) {
+		t.Errorf("end = %v, se esperaba el limite exclusivo %v", stats.End, want)
+	}
+
+	rec = serve(pc.GetProductHistory, "GET", "/api/products/2/history?start="+today+"&end=2000-01-01", vars, "")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("start posterior a end: status = %d", rec.Code)
+	}
+}
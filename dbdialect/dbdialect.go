@@ -0,0 +1,39 @@
+// Package dbdialect reune las diferencias entre las bases soportadas, para que
+// el resto del codigo no dependa de una en particular.
+package dbdialect
+
+import "gorm.io/gorm"
+
+// Nombres de los dialectos, tal como los informa el driver de GORM.
+const (
+	Postgres = "postgres"
+	SQLite   = "sqlite"
+)
+
+// Name devuelve el dialecto de db.
+func Name(db *gorm.DB) string {
+	return db.Dialector.Name()
+}
+
+// IsSQLite indica si db es una base SQLite.
+func IsSQLite(db *gorm.DB) bool {
+	return Name(db) == SQLite
+}
+
+// DayUTC devuelve la expresion con el dia (en UTC) de la columna de fecha.
+func DayUTC(db *gorm.DB, column string) string {
+	if IsSQLite(db) {
+		return "date(" + column + ")"
+	}
+	return "(" + column + " AT TIME ZONE 'UTC')::date"
+}
+
+// ContainsFold devuelve la condicion "column contiene el parametro sin
+// distinguir mayusculas". SQLite no tiene ILIKE pero su LIKE ya ignora las
+// mayusculas, aunque solo en caracteres ASCII.
+func ContainsFold(db *gorm.DB, column string) string {
+	if IsSQLite(db) {
+		return column + " LIKE ?"
+	}
+	return column + " ILIKE ?"
+}
@@ -0,0 +1,276 @@
+// Package dbreplica envia las consultas de solo lectura a replicas de la base
+// de datos. Solo se desvian las consultas marcadas con Read, hechas dentro de
+// una sesion (ver NewSession), que no estan en una transaccion ni bloquean
+// filas. Una sesion que ya escribio lee de la principal, para que cada request
+// vea lo que ella misma guardo. Si ninguna replica responde se usa la base
+// principal, y una lectura que falla en una replica por un error de conexion se
+// repite en la principal.
+package dbreplica
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"qisur-challenge/dbretry"
+	"qisur-challenge/logger"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+)
+
+const (
+	pluginName = "dbreplica"
+	readKey    = "dbreplica:read"
+	replicaKey = "dbreplica:replica"
+)
+
+// maxCheckTimeout limita lo que puede tardar el ping de cada verificacion.
+const maxCheckTimeout = 2 * time.Second
+
+// Replica es una conexion de solo lectura.
+type Replica struct {
+	Name string
+	DB   *sql.DB
+}
+
+type replica struct {
+	name    string
+	db      *sql.DB
+	pool    gorm.ConnPool
+	healthy atomic.Bool
+}
+
+// Router es un plugin de GORM que reparte las lecturas entre las replicas
+// disponibles en round robin.
+type Router struct {
+	primary  gorm.ConnPool
+	replicas []*replica
+	next     atomic.Uint64
+}
+
+// New crea el router. Las replicas se consideran disponibles hasta la primera
+// verificacion.
+func New(replicas []Replica) *Router {
+	r := &Router{}
+	for _, rep := range replicas {
+		rr := &replica{name: rep.Name, db: rep.DB, pool: dbretry.Wrap(rep.DB)}
+		rr.healthy.Store(true)
+		r.replicas = append(r.replicas, rr)
+	}
+	return r
+}
+
+// Read marca las consultas de db como de solo lectura para que puedan ir a una
+// replica.
+func Read(db *gorm.DB) *gorm.DB {
+	return db.Set(readKey, true)
+}
+
+// FromDB devuelve el router registrado en db, o nil si no hay replicas.
+func FromDB(db *gorm.DB) *Router {
+	if plugin, ok := db.Config.Plugins[pluginName]; ok {
+		return plugin.(*Router)
+	}
+	return nil
+}
+
+func (r *Router) Name() string {
+	return pluginName
+}
+
+func (r *Router) Initialize(db *gorm.DB) error {
+	r.primary = db.Config.ConnPool
+
+	cb := db.Callback()
+	if err := cb.Query().Before("gorm:query").Register("dbreplica:route_query", r.route); err != nil {
+		return err
+	}
+	if err := cb.Query().After("gorm:query").Register("dbreplica:observe_query", r.observe(callbacks.Query)); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("gorm:row").Register("dbreplica:route_row", r.route); err != nil {
+		return err
+	}
+	if err := cb.Row().After("gorm:row").Register("dbreplica:observe_row", r.observe(callbacks.RowQuery)); err != nil {
+		return err
+	}
+	if err := cb.Create().Before("gorm:create").Register("dbreplica:write_create", markWrite); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("dbreplica:write_update", markWrite); err != nil {
+		return err
+	}
+	if err := cb.Delete().Before("gorm:delete").Register("dbreplica:write_delete", markWrite); err != nil {
+		return err
+	}
+	return cb.Raw().Before("gorm:raw").Register("dbreplica:write_raw", markWrite)
+}
+
+// route cambia la conexion de la consulta por una replica cuando corresponde.
+func (r *Router) route(db *gorm.DB) {
+	if db.Error != nil || db.Statement.ConnPool != r.primary {
+		// Transacciones y conexiones dedicadas siguen en la principal.
+		return
+	}
+	if !readOnly(db) {
+		markWrite(db)
+		return
+	}
+	if read, _ := db.Get(readKey); read != true || !onReplica(db.Statement.Context) {
+		return
+	}
+	if rep := r.pick(); rep != nil {
+		db.Statement.ConnPool = rep.pool
+		db.InstanceSet(replicaKey, rep)
+	}
+}
+
+// observe marca la replica como no disponible si la consulta fallo por un
+// error de conexion, para que las siguientes vayan a la principal hasta que
+// vuelva a responder, y repite la consulta en la principal con exec.
+func (r *Router) observe(exec func(*gorm.DB)) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(replicaKey)
+		if !ok || !(dbretry.IsTransient(db.Error) || dbretry.IsConnectionLost(db.Error)) {
+			return
+		}
+		rep := value.(*replica)
+		r.setHealthy(db.Statement.Context, rep, false, db.Error)
+
+		logger.FromContext(db.Statement.Context).Debug("Lectura repetida en la base principal", "replica", rep.name, logger.Err(db.Error))
+		db.Error = nil
+		db.RowsAffected = 0
+		db.Statement.ConnPool = r.primary
+		exec(db)
+	}
+}
+
+// readOnly indica si la sentencia solo lee y no bloquea filas.
+func readOnly(db *gorm.DB) bool {
+	if _, locking := db.Statement.Clauses["FOR"]; locking {
+		return false
+	}
+	// Las consultas con SQL escrito a mano (Raw) pueden modificar datos.
+	sql := strings.ToLower(strings.TrimSpace(db.Statement.SQL.String()))
+	if sql == "" {
+		return true
+	}
+	return strings.HasPrefix(sql, "select") && !strings.Contains(sql, " for update") && !strings.Contains(sql, " for share")
+}
+
+func (r *Router) pick() *replica {
+	n := len(r.replicas)
+	if n == 0 {
+		return nil
+	}
+	start := int(r.next.Add(1) % uint64(n))
+	for i := 0; i < n; i++ {
+		if rep := r.replicas[(start+i)%n]; rep.healthy.Load() {
+			return rep
+		}
+	}
+	return nil
+}
+
+// Start verifica las replicas y las sigue verificando cada interval hasta que
+// se cancele ctx. Una replica que no responde deja de recibir consultas hasta
+// que vuelva a hacerlo.
+func (r *Router) Start(ctx context.Context, interval time.Duration) {
+	r.checkAll(ctx, interval)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.checkAll(ctx, interval)
+			}
+		}
+	}()
+}
+
+func (r *Router) checkAll(ctx context.Context, interval time.Duration) {
+	for _, rep := range r.replicas {
+		pingCtx, cancel := context.WithTimeout(ctx, min(interval, maxCheckTimeout))
+		err := rep.db.PingContext(pingCtx)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		r.setHealthy(ctx, rep, err == nil, err)
+	}
+}
+
+func (r *Router) setHealthy(ctx context.Context, rep *replica, healthy bool, err error) {
+	if rep.healthy.Swap(healthy) == healthy {
+		return
+	}
+	if healthy {
+		logger.FromContext(ctx).Info("Réplica disponible nuevamente", "replica", rep.name)
+	} else {
+		logger.FromContext(ctx).Warn("Réplica no disponible, las lecturas van a la base principal", "replica", rep.name, logger.Err(err))
+	}
+}
+
+// Status devuelve si cada replica esta disponible, por nombre.
+func (r *Router) Status() map[string]bool {
+	status := make(map[string]bool, len(r.replicas))
+	for _, rep := range r.replicas {
+		status[rep.name] = rep.healthy.Load()
+	}
+	return status
+}
+
+// Close cierra las conexiones de las replicas.
+func (r *Router) Close() error {
+	var firstErr error
+	for _, rep := range r.replicas {
+		if err := rep.db.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// session recuerda si la request ya escribio, para leer de la principal
+// desde ese momento.
+type session struct {
+	primary atomic.Bool
+}
+
+type sessionKey struct{}
+
+// NewSession agrega a ctx una sesion que registra las escrituras. Sin sesion
+// todas las consultas van a la principal, lo que protege a los procesos en
+// segundo plano que leen y despues modifican lo leido.
+func NewSession(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sessionKey{}, &session{})
+}
+
+// UsePrimary hace que todas las lecturas hechas con ctx vayan a la base
+// principal.
+func UsePrimary(ctx context.Context) context.Context {
+	s, ok := ctx.Value(sessionKey{}).(*session)
+	if !ok {
+		s = &session{}
+		ctx = context.WithValue(ctx, sessionKey{}, s)
+	}
+	s.primary.Store(true)
+	return ctx
+}
+
+func onReplica(ctx context.Context) bool {
+	s, ok := ctx.Value(sessionKey{}).(*session)
+	return ok && !s.primary.Load()
+}
+
+func markWrite(db *gorm.DB) {
+	if s, ok := db.Statement.Context.Value(sessionKey{}).(*session); ok {
+		s.primary.Store(true)
+	}
+}
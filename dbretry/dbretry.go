@@ -0,0 +1,190 @@
+// Package dbretry repite las operaciones de base de datos que fallan por
+// errores transitorios: conexiones que no llegaron a enviar la sentencia,
+// conflictos de serializacion, deadlocks y, en SQLite, la base bloqueada por
+// otra conexion. En todos esos casos la base no aplico la operacion, por lo
+// que repetirla no duplica cambios. Si la conexion se pierde despues de
+// enviar la sentencia no se sabe si se aplico: solo se repiten las lecturas y
+// las transacciones que no llegaron al COMMIT.
+package dbretry
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"time"
+
+	"qisur-challenge/logger"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// maxBackoff es la espera maxima entre dos intentos.
+const maxBackoff = 2 * time.Second
+
+// Policy define cuantas veces se intenta una operacion y la espera inicial
+// entre intentos, que se duplica en cada uno.
+type Policy struct {
+	Attempts int
+	Backoff  time.Duration
+}
+
+var policy = Policy{Attempts: 3, Backoff: 50 * time.Millisecond}
+
+// SetPolicy reemplaza la politica de reintentos. Debe llamarse antes de usar
+// la base de datos.
+func SetPolicy(p Policy) {
+	policy = p
+}
+
+// Do ejecuta fn y la repite mientras falle con un error transitorio, hasta
+// agotar los intentos o que se cancele ctx. fn debe poder ejecutarse mas de
+// una vez.
+func Do(ctx context.Context, fn func() error) error {
+	return Retry(ctx, IsTransient, fn)
+}
+
+// Retry es Do con otro criterio para decidir que errores se repiten.
+func Retry(ctx context.Context, retryable func(error) bool, fn func() error) error {
+	delay := policy.Backoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= policy.Attempts || !retryable(err) {
+			return err
+		}
+		logger.FromContext(ctx).Warn("Error transitorio de base de datos, se reintenta",
+			"attempt", attempt, "delay", delay.String(), logger.Err(err))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		delay = min(delay*2, maxBackoff)
+	}
+}
+
+// IsTransient indica si err es un error que puede desaparecer al reintentar y
+// tras el cual la base no aplico la operacion.
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || pgconn.SafeToRetry(err) {
+		return true
+	}
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "40001", // serialization_failure
+			"40P01", // deadlock_detected
+			"57P03": // cannot_connect_now
+			return true
+		}
+	}
+	var sqliteErr interface{ Code() int }
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code() & 0xff {
+		case 5, // SQLITE_BUSY
+			6: // SQLITE_LOCKED
+			return true
+		}
+	}
+	return false
+}
+
+// IsConnectionLost indica si err es una conexion que se corto o que el
+// servidor cerro despues de recibir la sentencia. La base pudo haberla
+// aplicado, salvo que fuera de solo lectura o estuviera dentro de una
+// transaccion sin confirmar, que el servidor descarta.
+func IsConnectionLost(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == "57P01" || // admin_shutdown
+		strings.HasPrefix(pgErr.Code, "08") // connection_exception
+}
+
+// readRetryable es el criterio de las sentencias que no modifican datos, que
+// tambien se repiten si se perdio la conexion.
+func readRetryable(err error) bool {
+	return IsTransient(err) || IsConnectionLost(err)
+}
+
+// readOnly indica si query es una consulta SELECT, que se puede repetir
+// aunque no se sepa si llego a ejecutarse.
+func readOnly(query string) bool {
+	query = strings.TrimSpace(query)
+	return len(query) >= 6 && strings.EqualFold(query[:6], "select")
+}
+
+// pool envuelve el pool de conexiones para que GORM repita las sentencias
+// que fallan por errores transitorios. Las sentencias dentro de una
+// transaccion no pasan por aca: se repite la transaccion completa con Retry.
+type pool struct {
+	db *sql.DB
+}
+
+// Wrap devuelve un gorm.ConnPool sobre db que reintenta las sentencias.
+func Wrap(db *sql.DB) gorm.ConnPool {
+	return &pool{db: db}
+}
+
+func (p *pool) PrepareContext(ctx context.Context, query string) (stmt *sql.Stmt, err error) {
+	err = Retry(ctx, readRetryable, func() error {
+		stmt, err = p.db.PrepareContext(ctx, query)
+		return err
+	})
+	return stmt, err
+}
+
+// ExecContext no repite las sentencias si se perdio la conexion porque pueden
+// haberse aplicado.
+func (p *pool) ExecContext(ctx context.Context, query string, args ...interface{}) (result sql.Result, err error) {
+	err = Do(ctx, func() error {
+		result, err = p.db.ExecContext(ctx, query, args...)
+		return err
+	})
+	return result, err
+}
+
+// QueryContext tambien ejecuta INSERT y UPDATE con RETURNING, por lo que solo
+// repite los SELECT si se perdio la conexion.
+func (p *pool) QueryContext(ctx context.Context, query string, args ...interface{}) (rows *sql.Rows, err error) {
+	retryable := IsTransient
+	if readOnly(query) {
+		retryable = readRetryable
+	}
+	err = Retry(ctx, retryable, func() error {
+		rows, err = p.db.QueryContext(ctx, query, args...)
+		return err
+	})
+	return rows, err
+}
+
+// QueryRowContext no se reintenta porque el error recien se conoce al leer la fila.
+func (p *pool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return p.db.QueryRowContext(ctx, query, args...)
+}
+
+func (p *pool) BeginTx(ctx context.Context, opts *sql.TxOptions) (tx *sql.Tx, err error) {
+	err = Retry(ctx, readRetryable, func() error {
+		tx, err = p.db.BeginTx(ctx, opts)
+		return err
+	})
+	return tx, err
+}
+
+// GetDBConn permite que db.DB() devuelva el *sql.DB original.
+func (p *pool) GetDBConn() (*sql.DB, error) {
+	return p.db, nil
+}
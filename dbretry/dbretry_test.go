@@ -0,0 +1,68 @@
+package dbretry
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestConnectionLostIsNotTransient(t *testing.T) {
+	tests := []struct {
+		code          string
+		wantTransient bool
+		wantLost      bool
+	}{
+		{code: "40001", wantTransient: true},
+		{code: "40P01", wantTransient: true},
+		{code: "57P03", wantTransient: true},
+		{code: "57P01", wantLost: true},
+		{code: "08006", wantLost: true},
+		{code: "23505"},
+	}
+	for _, tt := range tests {
+		err := fmt.Errorf("consulta: %w", &pgconn.PgError{Code: tt.code})
+		if got := IsTransient(err); got != tt.wantTransient {
+			t.Errorf("IsTransient(%s) = %v, se esperaba %v", tt.code, got, tt.wantTransient)
+		}
+		if got := IsConnectionLost(err); got != tt.wantLost {
+			t.Errorf("IsConnectionLost(%s) = %v, se esperaba %v", tt.code, got, tt.wantLost)
+		}
+	}
+}
+
+func TestDoDoesNotRepeatLostWrites(t *testing.T) {
+	defer SetPolicy(policy)
+	SetPolicy(Policy{Attempts: 3})
+	calls := 0
+	err := Do(context.Background(), func() error {
+		calls++
+		return &pgconn.PgError{Code: "08006"}
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("calls = %d, err = %v; una escritura con la conexion perdida no se repite", calls, err)
+	}
+
+	calls = 0
+	Retry(context.Background(), readRetryable, func() error {
+		calls++
+		return &pgconn.PgError{Code: "08006"}
+	})
+	if calls != 3 {
+		t.Errorf("calls = %d, una lectura con la conexion perdida se repite hasta agotar los intentos", calls)
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	for query, want := range map[string]bool{
+		`SELECT * FROM "products"`:                    true,
+		"\n\t\tselect count(*) FROM jobs":             true,
+		`INSERT INTO "products" ("name") VALUES ($1)`: false,
+		"UPDATE jobs SET status = $1 RETURNING *":     false,
+	} {
+		if got := readOnly(query); got != want {
+			t.Errorf("readOnly(%q) = %v, se esperaba %v", query, got, want)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"qisur-challenge/dbreplica"
+	"qisur-challenge/migrations"
+	websocket "qisur-challenge/webSocket"
+
+	"gorm.io/gorm"
+)
+
+// Fases del ciclo de vida del servidor. Solo en PhaseReady se considera que
+// la instancia puede recibir trafico.
+const (
+	PhaseStarting = "starting"
+	PhaseReady    = "ready"
+	PhaseStopping = "stopping"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// checkTimeout limita lo que puede tardar cada verificacion de dependencias.
+const checkTimeout = 2 * time.Second
+
+type CheckResult struct {
+	Status    string                 `json:"status"`
+	LatencyMs int64                  `json:"latency_ms"`
+	Error     string                 `json:"error,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+type Report struct {
+	Status string                 `json:"status"`
+	Phase  string                 `json:"phase"`
+	Uptime string                 `json:"uptime"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+type Checker struct {
+	db        *gorm.DB
+	startedAt time.Time
+
+	mu    sync.RWMutex
+	phase string
+}
+
+func NewChecker(db *gorm.DB) *Checker {
+	return &Checker{db: db, startedAt: time.Now(), phase: PhaseStarting}
+}
+
+func (c *Checker) SetPhase(phase string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.phase = phase
+}
+
+func (c *Checker) Phase() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.phase
+}
+
+// Liveness solo indica que el proceso responde; no consulta dependencias.
+func (c *Checker) Liveness() Report {
+	return Report{
+		Status: StatusUp,
+		Phase:  c.Phase(),
+		Uptime: time.Since(c.startedAt).Round(time.Second).String(),
+	}
+}
+
+// Readiness verifica la base de datos, las migraciones y el broker de eventos.
+// Devuelve false si alguna falla o si el servidor no esta en PhaseReady.
+func (c *Checker) Readiness(ctx context.Context) (Report, bool) {
+	report := c.Liveness()
+	report.Checks = map[string]CheckResult{
+		"database":   c.check(ctx, c.checkDatabase),
+		"migrations": c.check(ctx, c.checkMigrations),
+		"broker":     c.check(ctx, checkBroker),
+	}
+	if router := dbreplica.FromDB(c.db); router != nil {
+		report.Checks["replicas"] = c.check(ctx, checkReplicas(router))
+	}
+
+	ready := report.Phase == PhaseReady
+	for _, result := range report.Checks {
+		if result.Status != StatusUp {
+			ready = false
+		}
+	}
+	if !ready {
+		report.Status = StatusDown
+	}
+	return report, ready
+}
+
+func (c *Checker) check(ctx context.Context, fn func(ctx context.Context) (map[string]interface{}, error)) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	start := time.Now()
+	details, err := fn(ctx)
+	result := CheckResult{
+		Status:    StatusUp,
+		LatencyMs: time.Since(start).Milliseconds(),
+		Details:   details,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func (c *Checker) checkDatabase(ctx context.Context) (map[string]interface{}, error) {
+	sqlDB, err := c.db.DB()
+	if err != nil {
+		return nil, err
+	}
+	if err := sqlDB.PingContext(ctx); err != nil {
+		return nil, err
+	}
+	stats := sqlDB.Stats()
+	return map[string]interface{}{
+		"open_connections": stats.OpenConnections,
+		"in_use":           stats.InUse,
+		"idle":             stats.Idle,
+	}, nil
+}
+
+func (c *Checker) checkMigrations(ctx context.Context) (map[string]interface{}, error) {
+	pending, err := migrations.Pending(c.db.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	details := map[string]interface{}{"pending": pending}
+	if pending > 0 {
+		return details, fmt.Errorf("hay %d migraciones pendientes", pending)
+	}
+	return details, nil
+}
+
+// checkReplicas informa el estado de cada replica sin afectar la disponibilidad,
+// porque sin replicas las lecturas van a la base principal.
+func checkReplicas(router *dbreplica.Router) func(ctx context.Context) (map[string]interface{}, error) {
+	return func(ctx context.Context) (map[string]interface{}, error) {
+		details := map[string]interface{}{}
+		for name, healthy := range router.Status() {
+			details[name] = StatusDown
+			if healthy {
+				details[name] = StatusUp
+			}
+		}
+		return details, nil
+	}
+}
+
+func checkBroker(ctx context.Context) (map[string]interface{}, error) {
+	em := websocket.GetEventManager()
+	details := map[string]interface{}{"clients": em.ClientCount()}
+	if em.Closed() {
+		return details, fmt.Errorf("el broker de eventos está cerrado")
+	}
+	return details, nil
+}
@@ -0,0 +1,78 @@
+package i18n
+
+// catalog tiene los mensajes de cada idioma por clave. Las claves de errores
+// son el codigo del error, o el codigo seguido de la variante del mensaje
+// ("invalid_parameter.format"). Los errores en espanol usan el texto que arma
+// el servicio, por lo que solo se traducen a los demas idiomas.
+var catalog = map[Lang]map[string]string{
+	Spanish: {
+		"import.name_required":      "el nombre es obligatorio",
+		"import.duplicate_name":     "nombre duplicado en la fila {line}",
+		"import.negative_price":     "el precio no puede ser negativo",
+		"import.negative_stock":     "el stock no puede ser negativo",
+		"import.category_not_found": "categoría '{category}' no encontrada",
+		"import.invalid_price":      "precio inválido: {value}",
+		"import.invalid_stock":      "stock inválido: {value}",
+		"import.invalid_json":       "JSON inválido: {error}",
+		"import.invalid_row":        "fila inválida: {error}",
+	},
+	English: {
+		"internal_error":                  "Internal server error",
+		"invalid_id":                      "Invalid ID",
+		"invalid_body":                    "Invalid data",
+		"invalid_parameter":               "Invalid parameter '{parameter}'",
+		"invalid_parameter.format":        "Invalid parameter '{parameter}'. Expected format: {format}",
+		"invalid_parameter.allowed":       "Invalid parameter '{parameter}'. Allowed values: {allowed}",
+		"invalid_parameter.value":         "Invalid value '{value}' for parameter '{parameter}'",
+		"invalid_parameter.range":         "Parameter 'start' cannot be after 'end'",
+		"validation_failed":               "Invalid data",
+		"validation_failed.price":         "Price is required and cannot be negative",
+		"validation_failed.effective_at":  "The 'effective_at' date is required (RFC3339)",
+		"validation_failed.max_intervals": "The requested range exceeds {max_intervals} intervals",
+		"validation_failed.credentials":   "Username and password are required",
+		"validation_failed.language":      "Unsupported language '{language}'",
+		"validation_failed.role":          "Invalid role '{role}'",
+		"invalid_file":                    "The file could not be read",
+		"invalid_file.name_column":        "The CSV must have a column for the product name",
+		"unauthorized":                    "Unauthorized",
+		"invalid_token":                   "Invalid token",
+		"forbidden":                       "You do not have permission to perform this action",
+		"payload_too_large":               "The request body exceeds the maximum allowed size",
+		"query_timeout":                   "The query exceeded the maximum allowed time",
+		"service_unavailable":             "Service unavailable",
+		"service_unavailable.starting":    "The server is starting, try again in a few seconds",
+		"route_not_found":                 "Route not found",
+		"method_not_allowed":              "Method not allowed",
+
+		"invalid_credentials": "Invalid credentials",
+		"user_already_exists": "User '{username}' already exists",
+		"user_not_found":      "User not found",
+
+		"product_not_found":       "Product not found",
+		"product_not_found.as_of": "The product did not exist at {as_of}",
+		"product_name_taken":      "A product named '{name}' already exists",
+		"history_not_found":       "History entry not found",
+		"category_not_found":      "Category not found",
+		"category_name_taken":     "A category named '{name}' already exists",
+
+		"job_not_found":        "Job not found",
+		"job_not_downloadable": "The job does not produce a downloadable file",
+		"job_not_finished":     "The job has not finished successfully yet",
+		"job_result_missing":   "The job file is not available on this server",
+
+		"scheduler_disabled":   "The scheduler is disabled on this instance",
+		"websocket_full":       "The maximum number of WebSocket connections has been reached",
+		"invalid_message":      "Invalid message: expected JSON with 'type' and 'data'",
+		"unknown_message_type": "Unknown message type: '{type}'",
+
+		"import.name_required":      "name is required",
+		"import.duplicate_name":     "duplicate name in row {line}",
+		"import.negative_price":     "price cannot be negative",
+		"import.negative_stock":     "stock cannot be negative",
+		"import.category_not_found": "category '{category}' not found",
+		"import.invalid_price":      "invalid price: {value}",
+		"import.invalid_stock":      "invalid stock: {value}",
+		"import.invalid_json":       "invalid JSON: {error}",
+		"import.invalid_row":        "invalid row: {error}",
+	},
+}
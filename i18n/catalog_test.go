@@ -0,0 +1,15 @@
+package i18n
+
+import "testing"
+
+// Todo mensaje del catalogo tiene que estar en todos los idiomas, salvo los
+// errores, que en espanol los arma el servicio.
+func TestCatalogIsComplete(t *testing.T) {
+	for key := range catalog[Spanish] {
+		for _, lang := range Supported {
+			if _, ok := catalog[lang][key]; !ok {
+				t.Errorf("falta %q en %s", key, lang)
+			}
+		}
+	}
+}
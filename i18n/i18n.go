@@ -0,0 +1,122 @@
+// Package i18n elige el idioma de las respuestas y traduce los mensajes de la
+// API. El espanol es el idioma por defecto y el de los mensajes que arman los
+// servicios; el catalogo tiene las traducciones a los demas idiomas.
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Lang string
+
+const (
+	Spanish Lang = "es"
+	English Lang = "en"
+
+	Default = Spanish
+)
+
+// Supported son los idiomas con catalogo, en orden de preferencia ante un
+// empate en Accept-Language.
+var Supported = []Lang{Spanish, English}
+
+type contextKey struct{}
+
+// Parse devuelve el idioma soportado que corresponde a tag, ignorando la
+// region ("en-US" es English).
+func Parse(tag string) (Lang, bool) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	for _, lang := range Supported {
+		if tag == string(lang) {
+			return lang, true
+		}
+	}
+	return "", false
+}
+
+// Negotiate elige el idioma soportado con mayor peso en el header
+// Accept-Language. Si ninguno coincide devuelve Default y false.
+func Negotiate(header string) (Lang, bool) {
+	type candidate struct {
+		lang Lang
+		q    float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if lang, ok := Parse(tag); ok && q > 0 {
+			candidates = append(candidates, candidate{lang, q})
+		}
+	}
+	if len(candidates) == 0 {
+		return Default, false
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].lang, true
+}
+
+func WithLang(ctx context.Context, lang Lang) context.Context {
+	return context.WithValue(ctx, contextKey{}, lang)
+}
+
+// FromContext devuelve el idioma guardado en ctx, o Default.
+func FromContext(ctx context.Context) Lang {
+	if lang, ok := ctx.Value(contextKey{}).(Lang); ok {
+		return lang
+	}
+	return Default
+}
+
+// Translate devuelve el mensaje key del catalogo de lang, reemplazando cada
+// {nombre} por params[nombre].
+func Translate(lang Lang, key string, params map[string]any) (string, bool) {
+	msg, ok := catalog[lang][key]
+	if !ok {
+		return "", false
+	}
+	return expand(msg, params), true
+}
+
+// Text es como Translate pero, si lang no tiene el mensaje, usa el de Default.
+// Solo se usa con claves que estan en el catalogo en espanol.
+func Text(lang Lang, key string, params map[string]any) string {
+	if msg, ok := Translate(lang, key, params); ok {
+		return msg
+	}
+	msg, _ := Translate(Default, key, params)
+	return msg
+}
+
+func expand(msg string, params map[string]any) string {
+	if len(params) == 0 {
+		return msg
+	}
+	pairs := make([]string, 0, len(params)*2)
+	for name, value := range params {
+		pairs = append(pairs, "{"+name+"}", format(value))
+	}
+	return strings.NewReplacer(pairs...).Replace(msg)
+}
+
+func format(value any) string {
+	if t, ok := value.(time.Time); ok {
+		return t.Format(time.RFC3339)
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,52 @@
+package i18n_test
+
+import (
+	"testing"
+	"time"
+
+	"qisur-challenge/i18n"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   i18n.Lang
+		wantOK bool
+	}{
+		{header: "", want: i18n.Spanish},
+		{header: "en", want: i18n.English, wantOK: true},
+		{header: "en-US,en;q=0.9", want: i18n.English, wantOK: true},
+		{header: "es-AR,en;q=0.8", want: i18n.Spanish, wantOK: true},
+		{header: "fr-FR,en;q=0.5,es;q=0.7", want: i18n.Spanish, wantOK: true},
+		{header: "es;q=0.2, EN_gb", want: i18n.English, wantOK: true},
+		{header: "en;q=0,es;q=0.1", want: i18n.Spanish, wantOK: true},
+		{header: "fr, de;q=0.9", want: i18n.Spanish},
+		{header: "en;q=abc", want: i18n.Spanish},
+	}
+	for _, tt := range tests {
+		got, ok := i18n.Negotiate(tt.header)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("Negotiate(%q) = %q, %v; se esperaba %q, %v", tt.header, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	got, ok := i18n.Translate(i18n.English, "product_name_taken", map[string]any{"name": "Mouse"})
+	if !ok || got != "A product named 'Mouse' already exists" {
+		t.Errorf("Translate = %q, %v", got, ok)
+	}
+
+	asOf := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	got, _ = i18n.Translate(i18n.English, "product_not_found.as_of", map[string]any{"as_of": asOf})
+	if got != "The product did not exist at 2026-06-01T00:00:00Z" {
+		t.Errorf("las fechas deben formatearse en RFC3339: %q", got)
+	}
+
+	if _, ok := i18n.Translate(i18n.Spanish, "product_name_taken", nil); ok {
+		t.Error("los errores en espanol no estan en el catalogo: los arma el servicio")
+	}
+	if got := i18n.Text(i18n.Lang("fr"), "import.negative_stock", nil); got != "el stock no puede ser negativo" {
+		t.Errorf("Text sin traduccion debe usar el idioma por defecto: %q", got)
+	}
+}
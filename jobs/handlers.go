@@ -0,0 +1,110 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"qisur-challenge/i18n"
+	"qisur-challenge/models"
+	"qisur-challenge/services"
+)
+
+const (
+	TypeProductsImport = "products_import"
+	TypeProductsExport = "products_export"
+)
+
+type ImportPayload struct {
+	Format  string            `json:"format"`
+	DryRun  bool              `json:"dry_run"`
+	Actor   string            `json:"actor"`
+	Mapping map[string]string `json:"mapping"`
+	Data    string            `json:"data"`
+	// Lang es el idioma de la request que encolo el trabajo, para armar el
+	// reporte en ese idioma.
+	Lang i18n.Lang `json:"lang,omitempty"`
+}
+
+type ExportPayload struct {
+	Format  string   `json:"format"`
+	Columns []string `json:"columns"`
+	Name    string   `json:"name"`
+	Sort    string   `json:"sort"`
+}
+
+type ExportResult struct {
+	Format string `json:"format"`
+	Size   int64  `json:"size"`
+}
+
+// ExportFile devuelve la ruta del archivo generado por un trabajo de exportacion.
+func ExportFile(dir string, job *models.Job) (string, string, error) {
+	var payload ExportPayload
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		return "", "", err
+	}
+	return filepath.Join(dir, fmt.Sprintf("export-%d.%s", job.ID, payload.Format)), payload.Format, nil
+}
+
+// RegisterProductHandlers registra los trabajos de importacion y exportacion
+// de productos. Los archivos exportados se guardan en exportDir.
+func RegisterProductHandlers(r *Runner, productService services.ProductService, exportDir string) {
+	r.Register(TypeProductsImport, func(ctx context.Context, job *models.Job, progress func(int)) (interface{}, error) {
+		var payload ImportPayload
+		if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+			return nil, err
+		}
+		if lang, ok := i18n.Parse(string(payload.Lang)); ok {
+			ctx = i18n.WithLang(ctx, lang)
+		}
+		return productService.ImportProducts(ctx, strings.NewReader(payload.Data), services.ImportOptions{
+			Format:  payload.Format,
+			DryRun:  payload.DryRun,
+			Actor:   payload.Actor,
+			Mapping: payload.Mapping,
+			Progress: func(done, total int) {
+				progress(done * 100 / total)
+			},
+		})
+	})
+
+	r.Register(TypeProductsExport, func(ctx context.Context, job *models.Job, progress func(int)) (interface{}, error) {
+		var payload ExportPayload
+		if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(exportDir, 0o755); err != nil {
+			return nil, err
+		}
+		path, _, err := ExportFile(exportDir, job)
+		if err != nil {
+			return nil, err
+		}
+
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		err = productService.ExportProducts(ctx, f, services.ExportOptions{
+			Format:  payload.Format,
+			Columns: payload.Columns,
+			Name:    payload.Name,
+			Sort:    payload.Sort,
+		})
+		if err != nil {
+			os.Remove(path)
+			return nil, err
+		}
+		info, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		return ExportResult{Format: payload.Format, Size: info.Size()}, nil
+	})
+}
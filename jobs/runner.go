@@ -0,0 +1,307 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"qisur-challenge/logger"
+	"qisur-challenge/models"
+	"qisur-challenge/repository"
+	"qisur-challenge/tracing"
+	websocket "qisur-challenge/webSocket"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const (
+	retryBaseDelay = 5 * time.Second
+	retryMaxDelay  = 10 * time.Minute
+	// staleAfter es el tiempo sin actividad tras el cual un trabajo en
+	// ejecucion se considera abandonado y vuelve a la cola.
+	staleAfter = 15 * time.Minute
+	// heartbeatInterval es cada cuanto se renueva locked_at mientras el
+	// handler se ejecuta, aunque no informe avance.
+	heartbeatInterval = staleAfter / 5
+)
+
+// HandlerFunc ejecuta un trabajo. progress permite informar el avance (0-100)
+// y el valor devuelto se guarda como resultado en formato JSON.
+type HandlerFunc func(ctx context.Context, job *models.Job, progress func(int)) (interface{}, error)
+
+// Queue es la parte del Runner que usan los controladores para encolar y
+// consultar trabajos.
+type Queue interface {
+	Enqueue(ctx context.Context, jobType string, payload interface{}, maxAttempts int, createdBy string) (*models.Job, error)
+	Get(ctx context.Context, id uint) (*models.Job, error)
+}
+
+type Runner struct {
+	repo         repository.JobRepository
+	handlers     map[string]HandlerFunc
+	workers      int
+	pollInterval time.Duration
+	workerPrefix string
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewRunner(db *gorm.DB, workers int, pollInterval time.Duration) *Runner {
+	hostname, _ := os.Hostname()
+	return &Runner{
+		repo:         repository.NewJobRepository(db),
+		handlers:     map[string]HandlerFunc{},
+		workers:      workers,
+		pollInterval: pollInterval,
+		workerPrefix: fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+	}
+}
+
+// Register asocia un tipo de trabajo con su handler. Debe llamarse antes de Start.
+func (r *Runner) Register(jobType string, handler HandlerFunc) {
+	r.handlers[jobType] = handler
+}
+
+// Enqueue guarda el trabajo en la cola. createdBy es el usuario que lo pidio,
+// el unico que puede consultarlo ademas de los administradores.
+func (r *Runner) Enqueue(ctx context.Context, jobType string, payload interface{}, maxAttempts int, createdBy string) (*models.Job, error) {
+	if _, ok := r.handlers[jobType]; !ok {
+		return nil, fmt.Errorf("tipo de trabajo desconocido: %s", jobType)
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+	job := &models.Job{
+		Type:        jobType,
+		Status:      models.JobStatusQueued,
+		Payload:     string(data),
+		MaxAttempts: maxAttempts,
+		CreatedBy:   createdBy,
+		RunAt:       time.Now(),
+	}
+	if err := r.repo.Create(ctx, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (r *Runner) Get(ctx context.Context, id uint) (*models.Job, error) {
+	return r.repo.GetByID(ctx, id)
+}
+
+// Start lanza los workers en segundo plano.
+func (r *Runner) Start() {
+	if r.workers <= 0 || r.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+
+	for i := 0; i < r.workers; i++ {
+		workerID := fmt.Sprintf("%s-%d", r.workerPrefix, i)
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			r.work(ctx, workerID)
+		}()
+	}
+	slog.Info("Workers de trabajos iniciados", "workers", r.workers)
+}
+
+// Stop cancela los trabajos en curso y espera a que los workers terminen.
+func (r *Runner) Stop() {
+	if r.cancel == nil {
+		return
+	}
+	r.cancel()
+	r.wg.Wait()
+	r.cancel = nil
+}
+
+func (r *Runner) work(ctx context.Context, workerID string) {
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.repo.RequeueStale(ctx, time.Now().Add(-staleAfter)); err != nil {
+			slog.Error("Error al recuperar trabajos abandonados", logger.Err(err))
+		}
+		for ctx.Err() == nil {
+			job, err := r.repo.Claim(ctx, workerID)
+			if err != nil {
+				if ctx.Err() == nil {
+					slog.Error("Error al reclamar trabajo", "worker", workerID, logger.Err(err))
+				}
+				break
+			}
+			if job == nil {
+				break
+			}
+			r.run(ctx, job)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Runner) run(ctx context.Context, job *models.Job) {
+	ctx, span := tracing.Start(ctx, "job "+job.Type, trace.WithAttributes(
+		attribute.Int64("job.id", int64(job.ID)),
+		attribute.Int("job.attempt", job.Attempts),
+	))
+	var runErr error
+	defer func() { tracing.End(span, runErr) }()
+
+	handler, ok := r.handlers[job.Type]
+	if !ok {
+		runErr = fmt.Errorf("tipo de trabajo desconocido: %s", job.Type)
+		r.finish(ctx, job, nil, runErr)
+		return
+	}
+
+	// runCtx se cancela si el trabajo deja de pertenecer a este worker, para
+	// que el handler no siga escribiendo junto con el que lo tomo despues.
+	runCtx, lost := context.WithCancel(ctx)
+	defer lost()
+	beating := make(chan struct{})
+	go func() {
+		defer close(beating)
+		r.heartbeat(runCtx, job, lost)
+	}()
+
+	lastProgress := job.Progress
+	progress := func(p int) {
+		if p == lastProgress || p < 0 || p > 100 {
+			return
+		}
+		lastProgress = p
+		err := r.repo.UpdateProgress(runCtx, job.ID, job.LockedBy, p)
+		if errors.Is(err, repository.ErrJobLeaseLost) {
+			slog.Warn("El trabajo fue tomado por otro worker, se cancela", "job_id", job.ID)
+			lost()
+		} else if err != nil {
+			slog.Error("Error al actualizar progreso del trabajo", "job_id", job.ID, logger.Err(err))
+		}
+	}
+
+	result, err := r.safeRun(runCtx, handler, job, progress)
+	lost()
+	<-beating
+	runErr = err
+	r.finish(ctx, job, result, err)
+}
+
+// heartbeat renueva el lease del trabajo cada heartbeatInterval hasta que ctx
+// termine. Si el trabajo ya no pertenece a este worker llama a lost.
+func (r *Runner) heartbeat(ctx context.Context, job *models.Job, lost context.CancelFunc) {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		err := r.repo.Heartbeat(ctx, job.ID, job.LockedBy)
+		if errors.Is(err, repository.ErrJobLeaseLost) {
+			slog.Warn("El trabajo fue tomado por otro worker, se cancela", "job_id", job.ID)
+			lost()
+			return
+		}
+		if err != nil && ctx.Err() == nil {
+			slog.Error("Error al renovar el lease del trabajo", "job_id", job.ID, logger.Err(err))
+		}
+	}
+}
+
+func (r *Runner) safeRun(ctx context.Context, handler HandlerFunc, job *models.Job, progress func(int)) (result interface{}, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("panic en el trabajo: %v", rec)
+		}
+	}()
+	return handler(ctx, job, progress)
+}
+
+func (r *Runner) finish(ctx context.Context, job *models.Job, result interface{}, runErr error) {
+	// El resultado se registra aunque Stop haya cancelado el contexto, para que
+	// el trabajo no quede tomado hasta que se lo considere abandonado.
+	ctx = context.WithoutCancel(ctx)
+	if runErr == nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			runErr = err
+		} else if err := r.repo.Complete(ctx, job.ID, job.LockedBy, string(data)); err != nil {
+			logFinishError(job, "Error al completar trabajo", err)
+			return
+		} else {
+			job.Status = models.JobStatusSucceeded
+			broadcastJob(ctx, "job_completed", job)
+			return
+		}
+	}
+
+	var retryAt *time.Time
+	if job.Attempts < job.MaxAttempts {
+		t := time.Now().Add(retryDelay(job.Attempts))
+		retryAt = &t
+	}
+	if err := r.repo.Fail(ctx, job.ID, job.LockedBy, runErr.Error(), retryAt); err != nil {
+		logFinishError(job, "Error al registrar fallo del trabajo", err)
+		return
+	}
+	if retryAt == nil {
+		job.Status = models.JobStatusFailed
+		slog.Error("Trabajo fallido", "job_id", job.ID, "job_type", job.Type, "attempts", job.Attempts, logger.Err(runErr))
+		broadcastJob(ctx, "job_failed", job)
+	} else {
+		slog.Warn("Trabajo fallido, se reintentará", "job_id", job.ID, "job_type", job.Type, "attempts", job.Attempts, "retry_at", *retryAt, logger.Err(runErr))
+	}
+}
+
+// logFinishError registra que no se pudo guardar el resultado del trabajo. Si
+// otro worker lo tomo, su resultado es el que vale y solo se advierte.
+func logFinishError(job *models.Job, msg string, err error) {
+	if errors.Is(err, repository.ErrJobLeaseLost) {
+		slog.Warn("El trabajo fue tomado por otro worker, se descarta el resultado", "job_id", job.ID)
+		return
+	}
+	slog.Error(msg, "job_id", job.ID, logger.Err(err))
+}
+
+// retryDelay calcula la espera exponencial antes del siguiente intento.
+func retryDelay(attempts int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempts && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, retryMaxDelay)
+}
+
+func broadcastJob(ctx context.Context, eventType string, job *models.Job) {
+	websocket.GetEventManager().BroadcastMessage(ctx, websocket.Message{
+		Type: eventType,
+		Data: websocket.JobData{
+			ID:     job.ID,
+			Type:   job.Type,
+			Status: job.Status,
+		},
+	})
+}
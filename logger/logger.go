@@ -0,0 +1,139 @@
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type contextKey struct{}
+
+// redacted reemplaza el valor de los atributos con informacion sensible.
+const redacted = "[REDACTED]"
+
+// sensitiveKeys son fragmentos de nombres de atributo cuyo valor nunca se
+// escribe en los logs.
+var sensitiveKeys = []string{"password", "passwd", "secret", "token", "authorization", "api_key", "apikey"}
+
+// sensitiveValues detecta credenciales embebidas en textos, como un DSN
+// ("password=...") o un header "Bearer ...".
+var sensitiveValues = regexp.MustCompile(`(?i)((?:password|passwd|secret|token)\s*[=:]\s*)("[^"]*"|'[^']*'|[^\s&;,]+)|(bearer\s+)[A-Za-z0-9\-._~+/]+=*`)
+
+// Setup configura el logger por defecto de slog, que tambien recibe lo que se
+// escriba con el paquete log. level es debug, info, warn o error y format es
+// json o text.
+func Setup(level, format string) error {
+	handler, err := NewHandler(os.Stderr, level, format)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+func NewHandler(w io.Writer, level, format string) (slog.Handler, error) {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+	opts := &slog.HandlerOptions{Level: lvl, ReplaceAttr: redactAttr}
+	switch strings.ToLower(format) {
+	case "", "json":
+		return slog.NewJSONHandler(w, opts), nil
+	case "text":
+		return slog.NewTextHandler(w, opts), nil
+	default:
+		return nil, fmt.Errorf("formato de log '%s' inválido (json o text)", format)
+	}
+}
+
+func ParseLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("nivel de log '%s' inválido (debug, info, warn o error)", level)
+	}
+}
+
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+	key := strings.ToLower(a.Key)
+	for _, s := range sensitiveKeys {
+		if strings.Contains(key, s) {
+			return slog.String(a.Key, redacted)
+		}
+	}
+	switch a.Value.Kind() {
+	case slog.KindString:
+		if v := a.Value.String(); sensitiveValues.MatchString(v) {
+			return slog.String(a.Key, Redact(v))
+		}
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok && sensitiveValues.MatchString(err.Error()) {
+			return slog.String(a.Key, Redact(err.Error()))
+		}
+	}
+	return a
+}
+
+// Redact oculta las credenciales que aparezcan en s.
+func Redact(s string) string {
+	return sensitiveValues.ReplaceAllStringFunc(s, func(match string) string {
+		sub := sensitiveValues.FindStringSubmatch(match)
+		if sub[1] != "" {
+			return sub[1] + redacted
+		}
+		return sub[3] + redacted
+	})
+}
+
+// Err es el atributo estandar para registrar un error.
+func Err(err error) slog.Attr {
+	return slog.Any("error", err)
+}
+
+// NewRequestID genera un identificador aleatorio de 16 bytes en hexadecimal.
+func NewRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// RequestID devuelve el ID de la request guardado en ctx, o "".
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// FromContext devuelve el logger por defecto con el ID de la request y el de
+// la traza, si ctx los tiene.
+func FromContext(ctx context.Context) *slog.Logger {
+	l := slog.Default()
+	if id := RequestID(ctx); id != "" {
+		l = l.With("request_id", id)
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		l = l.With("trace_id", sc.TraceID().String())
+	}
+	return l
+}
@@ -1,39 +1,48 @@
 package main
 
 import (
-	"log"
-	"net/http"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"qisur-challenge/config"
-	"qisur-challenge/routes"
+	"qisur-challenge/logger"
 
 	"github.com/joho/godotenv"
 )
 
 func main() {
 	if err := godotenv.Load(); err != nil {
-		log.Println("No se pudo cargar .env, se usarán variables de entorno existentes.")
+		slog.Debug("No se pudo cargar .env, se usarán variables de entorno existentes")
 	}
 
-	config.LoadConfig()
-
-	db, err := config.CONNECTDB()
+	args, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
-		log.Fatalf("No se pudo conectar a la base de datos: %v", err)
+		fmt.Fprintf(os.Stderr, "Configuración inválida:\n%v\n", err)
+		os.Exit(2)
+	}
+	if err := logger.Setup(config.AppConfig.Log.Level, config.AppConfig.Log.Format); err != nil {
+		slog.Error("Configuración de logs inválida", logger.Err(err))
+		os.Exit(1)
 	}
 
-	config.AutoMigrate(db)
-
-	r := routes.RegisterRoutes(db)
-
-	port := os.Getenv("SERVER_PORT")
-	if port == "" {
-		port = "8080"
+	if len(args) == 0 {
+		args = []string{"serve"}
 	}
 
-	log.Printf("Servidor iniciado en puerto %s", port)
-	if err := http.ListenAndServe(":"+port, r); err != nil {
-		log.Fatal(err)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	err = runCommand(ctx, args)
+	stop()
+	if err != nil {
+		slog.Error("El comando terminó con error", logger.Err(err))
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,140 @@
+package metrics
+
+import (
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"gorm.io/gorm"
+)
+
+const startTimeKey = "metrics:start_time"
+
+// RegisterDB instrumenta las consultas de GORM y registra las estadisticas del
+// pool de conexiones y los indicadores de negocio que se leen de la base.
+func RegisterDB(db *gorm.DB) error {
+	if err := registerCallbacks(db); err != nil {
+		return err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return registerAll(
+		collectors.NewDBStatsCollector(sqlDB, "qisur"),
+		newCatalogCollector(db),
+	)
+}
+
+func registerAll(cs ...prometheus.Collector) error {
+	for _, c := range cs {
+		if err := Registry.Register(c); err != nil {
+			var already prometheus.AlreadyRegisteredError
+			if !errors.As(err, &already) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func registerCallbacks(db *gorm.DB) error {
+	cb := db.Callback()
+	hooks := []struct {
+		operation string
+		before    func(name string, fn func(*gorm.DB)) error
+		after     func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:create").Register},
+		{"query", cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Register},
+		{"update", cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:update").Register},
+		{"delete", cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Register},
+		{"row", cb.Row().Before("gorm:row").Register, cb.Row().After("gorm:row").Register},
+		{"raw", cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register},
+	}
+	for _, h := range hooks {
+		if err := h.before("metrics:before_"+h.operation, startTimer); err != nil {
+			return err
+		}
+		if err := h.after("metrics:after_"+h.operation, observeQuery(h.operation)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func startTimer(db *gorm.DB) {
+	db.InstanceSet(startTimeKey, time.Now())
+}
+
+func observeQuery(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(startTimeKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+		table := db.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+		DBQueryDuration.WithLabelValues(operation, table).Observe(time.Since(start).Seconds())
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			DBQueryErrors.WithLabelValues(operation, table).Inc()
+		}
+	}
+}
+
+// catalogCollector calcula los indicadores del catalogo en cada scrape.
+type catalogCollector struct {
+	db         *gorm.DB
+	products   *prometheus.Desc
+	stock      *prometheus.Desc
+	outOfStock *prometheus.Desc
+	categories *prometheus.Desc
+}
+
+func newCatalogCollector(db *gorm.DB) *catalogCollector {
+	return &catalogCollector{
+		db:         db,
+		products:   prometheus.NewDesc(namespace+"_products_total", "Cantidad de productos en el catalogo.", nil, nil),
+		stock:      prometheus.NewDesc(namespace+"_products_stock_total", "Suma del stock de todos los productos.", nil, nil),
+		outOfStock: prometheus.NewDesc(namespace+"_products_out_of_stock", "Productos sin stock.", nil, nil),
+		categories: prometheus.NewDesc(namespace+"_categories_total", "Cantidad de categorias.", nil, nil),
+	}
+}
+
+func (c *catalogCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.products
+	ch <- c.stock
+	ch <- c.outOfStock
+	ch <- c.categories
+}
+
+func (c *catalogCollector) Collect(ch chan<- prometheus.Metric) {
+	var catalog struct {
+		Products   int64
+		Stock      int64
+		OutOfStock int64
+	}
+	err := c.db.Raw(`SELECT COUNT(*) AS products, COALESCE(SUM(stock), 0) AS stock,
+		COALESCE(SUM(CASE WHEN stock <= 0 THEN 1 ELSE 0 END), 0) AS out_of_stock FROM products`).Scan(&catalog).Error
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.products, err)
+		return
+	}
+	var categories int64
+	if err := c.db.Table("categories").Count(&categories).Error; err != nil {
+		ch <- prometheus.NewInvalidMetric(c.categories, err)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.products, prometheus.GaugeValue, float64(catalog.Products))
+	ch <- prometheus.MustNewConstMetric(c.stock, prometheus.GaugeValue, float64(catalog.Stock))
+	ch <- prometheus.MustNewConstMetric(c.outOfStock, prometheus.GaugeValue, float64(catalog.OutOfStock))
+	ch <- prometheus.MustNewConstMetric(c.categories, prometheus.GaugeValue, float64(categories))
+}
@@ -1,31 +1,78 @@
 package middlewares
 
 import (
+	"context"
 	"net/http"
 	"strings"
 
+	"qisur-challenge/apperrors"
+	"qisur-challenge/config"
+	"qisur-challenge/i18n"
+
 	"github.com/golang-jwt/jwt"
 )
 
+type contextKey string
+
+const (
+	usernameKey contextKey = "username"
+	roleKey     contextKey = "role"
+)
+
 func AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			http.Error(w, "No autorizado", http.StatusUnauthorized)
+			apperrors.Write(w, r, apperrors.Unauthorized(apperrors.CodeUnauthorized, "No autorizado"))
 			return
 		}
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
-		claims := &jwt.StandardClaims{}
+		claims := jwt.MapClaims{}
 		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte("secret_key"), nil
+			return []byte(config.AppConfig.Auth.JWTSecret), nil
 		})
 
 		if err != nil || !token.Valid {
-			http.Error(w, "Token inválido", http.StatusUnauthorized)
+			apperrors.Write(w, r, apperrors.Unauthorized(apperrors.CodeInvalidToken, "Token inválido"))
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		username, _ := claims["username"].(string)
+		role, _ := claims["role"].(string)
+		ctx := context.WithValue(r.Context(), usernameKey, username)
+		ctx = context.WithValue(ctx, roleKey, role)
+		// El idioma preferido del usuario tiene prioridad sobre Accept-Language.
+		lang, _ := claims["lang"].(string)
+		if preferred, ok := i18n.Parse(lang); ok {
+			ctx = i18n.WithLang(ctx, preferred)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// UsernameFromContext devuelve el usuario autenticado por AuthMiddleware.
+func UsernameFromContext(ctx context.Context) string {
+	username, _ := ctx.Value(usernameKey).(string)
+	return username
+}
+
+// RoleFromContext devuelve el rol del usuario autenticado por AuthMiddleware.
+func RoleFromContext(ctx context.Context) string {
+	role, _ := ctx.Value(roleKey).(string)
+	return role
+}
+
+// RequireRole responde 403 si el usuario autenticado no tiene el rol indicado.
+// Debe usarse despues de AuthMiddleware.
+func RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if RoleFromContext(r.Context()) != role {
+				apperrors.Write(w, r, apperrors.Forbidden(apperrors.CodeForbidden, "No tiene permiso para realizar esta acción"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
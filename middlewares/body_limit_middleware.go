@@ -0,0 +1,32 @@
+package middlewares
+
+import (
+	"net/http"
+	"strings"
+
+	"qisur-challenge/apperrors"
+)
+
+// MaxBodyMiddleware limita el tamaño del body de las requests a limit bytes.
+// Las rutas que empiezan con alguno de los prefijos de exempt quedan excluidas
+// porque aplican su propio limite (por ejemplo la importacion de productos).
+func MaxBodyMiddleware(limit int64, exempt ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, prefix := range exempt {
+				if strings.HasPrefix(r.URL.Path, prefix) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			if limit > 0 && r.Body != nil {
+				if r.ContentLength > limit {
+					apperrors.Write(w, r, apperrors.New(apperrors.KindTooLarge, apperrors.CodePayloadTooLarge, "El cuerpo de la solicitud supera el tamaño máximo permitido").With("max_bytes", limit))
+					return
+				}
+				r.Body = http.MaxBytesReader(w, r.Body, limit)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"qisur-challenge/i18n"
+)
+
+// LanguageMiddleware guarda en el contexto el idioma de la respuesta, elegido
+// con el header Accept-Language. AuthMiddleware lo reemplaza por el idioma
+// preferido del usuario, si lo configuro.
+func LanguageMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lang, _ := i18n.Negotiate(r.Header.Get("Accept-Language"))
+		w.Header().Add("Vary", "Accept-Language")
+		next.ServeHTTP(w, r.WithContext(i18n.WithLang(r.Context(), lang)))
+	})
+}
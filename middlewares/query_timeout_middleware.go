@@ -0,0 +1,66 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"qisur-challenge/apperrors"
+)
+
+// QueryTimeoutMiddleware limita el tiempo que las consultas de cada request
+// pueden ocupar la base de datos, agregando un deadline a su contexto. Las
+// rutas que empiezan con alguno de los prefijos de exempt quedan excluidas
+// porque procesan volumenes grandes o mantienen la conexion abierta.
+func QueryTimeoutMiddleware(timeout time.Duration, exempt ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if timeout <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			for _, prefix := range exempt {
+				if strings.HasPrefix(r.URL.Path, prefix) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			r = r.WithContext(ctx)
+			next.ServeHTTP(&timeoutWriter{ResponseWriter: w, r: r}, r)
+		})
+	}
+}
+
+// timeoutWriter reemplaza los 500 que los handlers devuelven cuando una
+// consulta fallo por el deadline por un 504, para distinguirlos de errores
+// reales de la base de datos.
+type timeoutWriter struct {
+	http.ResponseWriter
+	r        *http.Request
+	replaced bool
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	if code == http.StatusInternalServerError && errors.Is(w.r.Context().Err(), context.DeadlineExceeded) {
+		w.replaced = true
+		apperrors.Write(w.ResponseWriter, w.r, apperrors.New(apperrors.KindTimeout, apperrors.CodeQueryTimeout, "La consulta superó el tiempo máximo permitido"))
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	if w.replaced {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *timeoutWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
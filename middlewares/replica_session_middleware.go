@@ -0,0 +1,21 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"qisur-challenge/dbreplica"
+)
+
+// ReplicaSessionMiddleware abre una sesion de lectura por request para que,
+// una vez que la request escribe, sus lecturas siguientes vayan a la base
+// principal. Los metodos que modifican datos leen siempre de la principal
+// porque suelen leer lo que van a modificar.
+func ReplicaSessionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := dbreplica.NewSession(r.Context())
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			ctx = dbreplica.UsePrimary(ctx)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,66 @@
+package middlewares
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"qisur-challenge/logger"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID limita los IDs que se aceptan del cliente para que no se
+// puedan inyectar valores arbitrarios en los logs.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9\-_.:]{1,128}$`)
+
+// quietPaths son rutas de sondeo que se registran solo en nivel debug.
+var quietPaths = map[string]bool{"/healthz": true, "/readyz": true, "/metrics": true}
+
+// RequestIDMiddleware reutiliza el X-Request-ID recibido o genera uno nuevo,
+// lo guarda en el contexto, lo devuelve en la respuesta y en el cuerpo de los
+// errores, y registra la request al terminar.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = logger.NewRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		ctx := logger.WithRequestID(r.Context(), id)
+
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+		if isTextError(rec) {
+			fmt.Fprintf(rec, "request_id: %s\n", id)
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case rec.status >= 500:
+			level = slog.LevelError
+		case quietPaths[r.URL.Path]:
+			level = slog.LevelDebug
+		}
+		logger.FromContext(ctx).LogAttrs(ctx, level, "request",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", rec.status),
+			slog.Int64("duration_ms", time.Since(start).Milliseconds()),
+			slog.String("remote_addr", r.RemoteAddr),
+		)
+	})
+}
+
+// isTextError indica si la respuesta es un error de texto plano escrito con
+// http.Error, al que se le puede agregar el request ID al final del cuerpo.
+func isTextError(rec *statusRecorder) bool {
+	h := rec.Header()
+	return rec.status >= 400 &&
+		strings.HasPrefix(h.Get("Content-Type"), "text/plain") &&
+		h.Get("Content-Length") == ""
+}
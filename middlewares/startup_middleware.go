@@ -0,0 +1,32 @@
+package middlewares
+
+import (
+	"net/http"
+	"strings"
+
+	"qisur-challenge/apperrors"
+	"qisur-challenge/health"
+)
+
+// StartupMiddleware responde 503 mientras el servidor esta en PhaseStarting,
+// por ejemplo mientras se aplican las migraciones, para que ninguna request
+// use un esquema a medio migrar. Las rutas que empiezan con alguno de los
+// prefijos de exempt, como los health checks, responden siempre.
+func StartupMiddleware(checker *health.Checker, exempt ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if checker.Phase() != health.PhaseStarting {
+				next.ServeHTTP(w, r)
+				return
+			}
+			for _, prefix := range exempt {
+				if strings.HasPrefix(r.URL.Path, prefix) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			w.Header().Set("Retry-After", "5")
+			apperrors.Write(w, r, apperrors.New(apperrors.KindUnavailable, apperrors.CodeUnavailable, "El servidor está iniciando, reintente en unos segundos").WithKey(apperrors.KeyStarting))
+		})
+	}
+}
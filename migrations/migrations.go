@@ -0,0 +1,275 @@
+package migrations
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"qisur-challenge/dbdialect"
+
+	"gorm.io/gorm"
+)
+
+// Cada dialecto tiene su propio juego de migraciones en sql/<dialecto>, con
+// las mismas versiones y nombres.
+//
+//go:embed sql/*/*.sql
+var files embed.FS
+
+// lockKey identifica el advisory lock de Postgres que evita que dos procesos
+// apliquen migraciones al mismo tiempo.
+const lockKey int64 = 7305911
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at"`
+}
+
+type schemaMigration struct {
+	Version   int64 `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Load devuelve las migraciones embebidas del dialecto ordenadas por version.
+// Cada migracion se compone de los archivos NNNN_nombre.up.sql y
+// NNNN_nombre.down.sql.
+func Load(dialect string) ([]Migration, error) {
+	dir := path.Join("sql", dialect)
+	entries, err := files.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("no hay migraciones para la base %s", dialect)
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		fileName := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(fileName, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(fileName, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		base := strings.TrimSuffix(fileName, "."+direction+".sql")
+		versionStr, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("nombre de migración inválido: %s", fileName)
+		}
+		version, err := strconv.ParseInt(versionStr, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("versión de migración inválida: %s", fileName)
+		}
+
+		content, err := files.ReadFile(path.Join(dir, fileName))
+		if err != nil {
+			return nil, err
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("versión de migración duplicada: %d", version)
+		}
+		if direction == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("la migración %d no tiene script up", m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Up aplica todas las migraciones pendientes y devuelve las que se aplicaron.
+func Up(db *gorm.DB) ([]Migration, error) {
+	migrations, err := Load(dbdialect.Name(db))
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	err = withLock(db, func(conn *gorm.DB) error {
+		done, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			if _, ok := done[m.Version]; ok {
+				continue
+			}
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(m.Up).Error; err != nil {
+					return err
+				}
+				return tx.Create(&schemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migración %d_%s: %w", m.Version, m.Name, err)
+			}
+			applied = append(applied, m)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down revierte las ultimas steps migraciones aplicadas.
+func Down(db *gorm.DB, steps int) ([]Migration, error) {
+	migrations, err := Load(dbdialect.Name(db))
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []Migration
+	err = withLock(db, func(conn *gorm.DB) error {
+		done, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+		for i := len(migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			m := migrations[i]
+			if _, ok := done[m.Version]; !ok {
+				continue
+			}
+			if m.Down == "" {
+				return fmt.Errorf("la migración %d_%s no tiene script down", m.Version, m.Name)
+			}
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(m.Down).Error; err != nil {
+					return err
+				}
+				return tx.Delete(&schemaMigration{}, m.Version).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migración %d_%s: %w", m.Version, m.Name, err)
+			}
+			reverted = append(reverted, m)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// GetStatus lista todas las migraciones conocidas indicando si estan aplicadas.
+// Solo lee: si la tabla schema_migrations todavia no existe, todas figuran
+// como pendientes.
+func GetStatus(db *gorm.DB) ([]Status, error) {
+	migrations, err := Load(dbdialect.Name(db))
+	if err != nil {
+		return nil, err
+	}
+	done := map[int64]time.Time{}
+	if db.Migrator().HasTable(&schemaMigration{}) {
+		if done, err = appliedVersions(db); err != nil {
+			return nil, err
+		}
+	}
+
+	status := make([]Status, len(migrations))
+	for i, m := range migrations {
+		status[i] = Status{Version: m.Version, Name: m.Name}
+		if appliedAt, ok := done[m.Version]; ok {
+			status[i].Applied = true
+			status[i].AppliedAt = &appliedAt
+		}
+	}
+	return status, nil
+}
+
+// Pending devuelve la cantidad de migraciones que aun no se aplicaron.
+func Pending(db *gorm.DB) (int, error) {
+	status, err := GetStatus(db)
+	if err != nil {
+		return 0, err
+	}
+	pending := 0
+	for _, s := range status {
+		if !s.Applied {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+// withLock ejecuta fn sobre una unica conexion que mantiene el advisory lock
+// mientras dura la operacion. SQLite no tiene advisory locks; cada migracion
+// corre en su transaccion y una segunda aplicacion concurrente falla al
+// registrar la misma version.
+func withLock(db *gorm.DB, fn func(conn *gorm.DB) error) error {
+	if dbdialect.IsSQLite(db) {
+		if err := ensureTable(db); err != nil {
+			return err
+		}
+		return fn(db)
+	}
+	return db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", lockKey).Error; err != nil {
+			return err
+		}
+		// El lock se libera aunque el contexto se haya cancelado, para no dejarlo
+		// tomado en una conexion que vuelve al pool.
+		defer conn.WithContext(context.WithoutCancel(conn.Statement.Context)).Exec("SELECT pg_advisory_unlock(?)", lockKey)
+
+		if err := ensureTable(conn); err != nil {
+			return err
+		}
+		return fn(conn)
+	})
+}
+
+func ensureTable(db *gorm.DB) error {
+	// El driver de SQLite solo convierte a time.Time las columnas DATETIME.
+	timeType := "TIMESTAMPTZ"
+	if dbdialect.IsSQLite(db) {
+		timeType = "DATETIME"
+	}
+	return db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    BIGINT PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at ` + timeType + ` NOT NULL
+	)`).Error
+}
+
+func appliedVersions(db *gorm.DB) (map[int64]time.Time, error) {
+	var rows []schemaMigration
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	done := make(map[int64]time.Time, len(rows))
+	for _, r := range rows {
+		done[r.Version] = r.AppliedAt
+	}
+	return done, nil
+}
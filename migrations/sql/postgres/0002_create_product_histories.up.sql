@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS product_histories (
+    id         BIGSERIAL PRIMARY KEY,
+    product_id BIGINT,
+    price      DECIMAL,
+    stock      BIGINT,
+    changed_at TIMESTAMPTZ,
+    CONSTRAINT fk_product_histories_product FOREIGN KEY (product_id) REFERENCES products (id)
+        ON UPDATE CASCADE ON DELETE CASCADE
+);
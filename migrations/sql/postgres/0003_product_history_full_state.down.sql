@@ -0,0 +1,16 @@
+DROP INDEX IF EXISTS idx_product_histories_changed_at;
+DROP INDEX IF EXISTS idx_product_histories_change_set_id;
+DROP INDEX IF EXISTS idx_product_histories_product_id;
+
+ALTER TABLE product_histories
+    DROP COLUMN IF EXISTS new_category_ids,
+    DROP COLUMN IF EXISTS old_category_ids,
+    DROP COLUMN IF EXISTS new_stock,
+    DROP COLUMN IF EXISTS new_price,
+    DROP COLUMN IF EXISTS new_description,
+    DROP COLUMN IF EXISTS old_description,
+    DROP COLUMN IF EXISTS new_name,
+    DROP COLUMN IF EXISTS old_name,
+    DROP COLUMN IF EXISTS reason,
+    DROP COLUMN IF EXISTS actor,
+    DROP COLUMN IF EXISTS change_set_id;
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS jobs (
+    id           BIGSERIAL PRIMARY KEY,
+    type         TEXT NOT NULL,
+    status       TEXT NOT NULL,
+    payload      TEXT,
+    result       TEXT,
+    error        TEXT,
+    progress     BIGINT NOT NULL DEFAULT 0,
+    attempts     BIGINT NOT NULL DEFAULT 0,
+    max_attempts BIGINT NOT NULL DEFAULT 1,
+    run_at       TIMESTAMPTZ NOT NULL,
+    locked_by    TEXT,
+    locked_at    TIMESTAMPTZ,
+    started_at   TIMESTAMPTZ,
+    finished_at  TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ,
+    updated_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_jobs_status_run_at ON jobs (status, run_at);
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_products_price_id ON products (price, id);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS product_histories (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    product_id BIGINT,
+    price      DECIMAL,
+    stock      BIGINT,
+    changed_at DATETIME,
+    CONSTRAINT fk_product_histories_product FOREIGN KEY (product_id) REFERENCES products (id)
+        ON UPDATE CASCADE ON DELETE CASCADE
+);
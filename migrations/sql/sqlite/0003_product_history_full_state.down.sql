@@ -0,0 +1,15 @@
+DROP INDEX IF EXISTS idx_product_histories_changed_at;
+DROP INDEX IF EXISTS idx_product_histories_change_set_id;
+DROP INDEX IF EXISTS idx_product_histories_product_id;
+
+ALTER TABLE product_histories DROP COLUMN new_category_ids;
+ALTER TABLE product_histories DROP COLUMN old_category_ids;
+ALTER TABLE product_histories DROP COLUMN new_stock;
+ALTER TABLE product_histories DROP COLUMN new_price;
+ALTER TABLE product_histories DROP COLUMN new_description;
+ALTER TABLE product_histories DROP COLUMN old_description;
+ALTER TABLE product_histories DROP COLUMN new_name;
+ALTER TABLE product_histories DROP COLUMN old_name;
+ALTER TABLE product_histories DROP COLUMN reason;
+ALTER TABLE product_histories DROP COLUMN actor;
+ALTER TABLE product_histories DROP COLUMN change_set_id;
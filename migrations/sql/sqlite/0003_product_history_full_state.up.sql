@@ -0,0 +1,46 @@
+ALTER TABLE product_histories ADD COLUMN change_set_id TEXT;
+ALTER TABLE product_histories ADD COLUMN actor TEXT;
+ALTER TABLE product_histories ADD COLUMN reason TEXT;
+ALTER TABLE product_histories ADD COLUMN old_name TEXT;
+ALTER TABLE product_histories ADD COLUMN new_name TEXT;
+ALTER TABLE product_histories ADD COLUMN old_description TEXT;
+ALTER TABLE product_histories ADD COLUMN new_description TEXT;
+ALTER TABLE product_histories ADD COLUMN new_price DECIMAL;
+ALTER TABLE product_histories ADD COLUMN new_stock BIGINT;
+ALTER TABLE product_histories ADD COLUMN old_category_ids TEXT;
+ALTER TABLE product_histories ADD COLUMN new_category_ids TEXT;
+
+-- Las entradas anteriores solo guardaban el precio y el stock previos. Se
+-- completan con los valores nuevos (los previos de la entrada siguiente o, en
+-- la ultima, los actuales del producto) y con el nombre, la descripcion y las
+-- categorias actuales, que es lo mejor que se conoce de ellas. Cada una queda
+-- como su propio cambio.
+UPDATE product_histories AS h SET
+    change_set_id   = 'legacy-' || h.id,
+    old_name        = (SELECT p.name FROM products p WHERE p.id = h.product_id),
+    new_name        = (SELECT p.name FROM products p WHERE p.id = h.product_id),
+    old_description = (SELECT p.description FROM products p WHERE p.id = h.product_id),
+    new_description = (SELECT p.description FROM products p WHERE p.id = h.product_id),
+    new_price = COALESCE(
+        (SELECT n.price FROM product_histories n
+          WHERE n.product_id = h.product_id
+            AND (n.changed_at > h.changed_at OR (n.changed_at = h.changed_at AND n.id > h.id))
+          ORDER BY n.changed_at, n.id LIMIT 1),
+        (SELECT p.price FROM products p WHERE p.id = h.product_id)),
+    new_stock = COALESCE(
+        (SELECT n.stock FROM product_histories n
+          WHERE n.product_id = h.product_id
+            AND (n.changed_at > h.changed_at OR (n.changed_at = h.changed_at AND n.id > h.id))
+          ORDER BY n.changed_at, n.id LIMIT 1),
+        (SELECT p.stock FROM products p WHERE p.id = h.product_id)),
+    old_category_ids = (SELECT json_group_array(category_id) FROM (
+                         SELECT pc.category_id FROM product_categories pc
+                          WHERE pc.product_id = h.product_id ORDER BY pc.category_id)),
+    new_category_ids = (SELECT json_group_array(category_id) FROM (
+                         SELECT pc.category_id FROM product_categories pc
+                          WHERE pc.product_id = h.product_id ORDER BY pc.category_id))
+WHERE h.change_set_id IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_product_histories_product_id ON product_histories (product_id);
+CREATE INDEX IF NOT EXISTS idx_product_histories_change_set_id ON product_histories (change_set_id);
+CREATE INDEX IF NOT EXISTS idx_product_histories_changed_at ON product_histories (changed_at);
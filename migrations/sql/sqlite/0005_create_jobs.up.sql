@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS jobs (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    type         TEXT NOT NULL,
+    status       TEXT NOT NULL,
+    payload      TEXT,
+    result       TEXT,
+    error        TEXT,
+    progress     BIGINT NOT NULL DEFAULT 0,
+    attempts     BIGINT NOT NULL DEFAULT 0,
+    max_attempts BIGINT NOT NULL DEFAULT 1,
+    run_at       DATETIME NOT NULL,
+    locked_by    TEXT,
+    locked_at    DATETIME,
+    started_at   DATETIME,
+    finished_at  DATETIME,
+    created_at   DATETIME,
+    updated_at   DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_jobs_status_run_at ON jobs (status, run_at);
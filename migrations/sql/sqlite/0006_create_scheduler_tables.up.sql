@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS scheduled_tasks (
+    name             TEXT PRIMARY KEY,
+    schedule         TEXT NOT NULL,
+    last_status      TEXT,
+    last_error       TEXT,
+    last_run_at      DATETIME,
+    last_duration_ms BIGINT NOT NULL DEFAULT 0,
+    last_run_by      TEXT,
+    next_run_at      DATETIME,
+    run_count        BIGINT NOT NULL DEFAULT 0,
+    updated_at       DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS scheduled_price_changes (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    product_id   BIGINT NOT NULL,
+    price        DECIMAL NOT NULL,
+    effective_at DATETIME NOT NULL,
+    reason       TEXT,
+    created_by   TEXT,
+    applied_at   DATETIME,
+    error        TEXT,
+    created_at   DATETIME,
+    CONSTRAINT fk_scheduled_price_changes_product FOREIGN KEY (product_id) REFERENCES products (id)
+        ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_price_changes_product_id ON scheduled_price_changes (product_id);
+CREATE INDEX IF NOT EXISTS idx_scheduled_price_changes_pending ON scheduled_price_changes (effective_at) WHERE applied_at IS NULL;
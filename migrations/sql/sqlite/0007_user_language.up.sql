@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN language TEXT NOT NULL DEFAULT '';
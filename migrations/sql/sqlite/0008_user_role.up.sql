@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user';
+
+-- Los usuarios existentes tenian acceso a todos los endpoints.
+UPDATE users SET role = 'admin';
@@ -0,0 +1 @@
+ALTER TABLE jobs ADD COLUMN created_by TEXT NOT NULL DEFAULT '';
@@ -11,15 +11,14 @@ type Category struct {
 	Products    []Product `json:"products" gorm:"many2many:product_categories"`
 }
 
-
 type ProductSummaryDTO struct {
 	ID   uint   `json:"id"`
 	Name string `json:"name"`
 }
 
 type CategoryWithProductsDTO struct {
-	ID          uint               `json:"id"`
-	Name        string             `json:"name"`
-	Description string             `json:"description"`
+	ID          uint                `json:"id"`
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
 	Products    []ProductSummaryDTO `json:"products"`
 }
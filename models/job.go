@@ -0,0 +1,70 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	JobStatusQueued    = "queued"
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	JobStatusFailed    = "failed"
+)
+
+type Job struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	Type        string     `json:"type"`
+	Status      string     `json:"status"`
+	Payload     string     `json:"-"`
+	Result      string     `json:"-"`
+	Error       string     `json:"error,omitempty"`
+	Progress    int        `json:"progress"`
+	Attempts    int        `json:"attempts"`
+	MaxAttempts int        `json:"max_attempts"`
+	CreatedBy   string     `json:"created_by"`
+	RunAt       time.Time  `json:"run_at"`
+	LockedBy    string     `json:"-"`
+	LockedAt    *time.Time `json:"-"`
+	StartedAt   *time.Time `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+type JobDTO struct {
+	ID          uint            `json:"id"`
+	Type        string          `json:"type"`
+	Status      string          `json:"status"`
+	Progress    int             `json:"progress"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	CreatedBy   string          `json:"created_by"`
+	Result      json.RawMessage `json:"result,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	RunAt       time.Time       `json:"run_at"`
+	StartedAt   *time.Time      `json:"started_at"`
+	FinishedAt  *time.Time      `json:"finished_at"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+func (j *Job) ToDTO() JobDTO {
+	dto := JobDTO{
+		ID:          j.ID,
+		Type:        j.Type,
+		Status:      j.Status,
+		Progress:    j.Progress,
+		Attempts:    j.Attempts,
+		MaxAttempts: j.MaxAttempts,
+		CreatedBy:   j.CreatedBy,
+		Error:       j.Error,
+		RunAt:       j.RunAt,
+		StartedAt:   j.StartedAt,
+		FinishedAt:  j.FinishedAt,
+		CreatedAt:   j.CreatedAt,
+	}
+	if j.Result != "" {
+		dto.Result = json.RawMessage(j.Result)
+	}
+	return dto
+}
@@ -13,12 +13,22 @@ type Product struct {
 	Categories  []Category `json:"categories" gorm:"many2many:product_categories"`
 }
 
+// CategoryIDs devuelve los IDs de las categorias asociadas al producto.
+func (p *Product) CategoryIDs() IDList {
+	ids := make(IDList, len(p.Categories))
+	for i, c := range p.Categories {
+		ids[i] = c.ID
+	}
+	return ids
+}
+
 type UpdateProductRequest struct {
-	Name        *string   `json:"name"`
-	Description *string   `json:"description"`
-	Price       *float64  `json:"price"`
-	Stock       *int      `json:"stock"`
-	Categories  *[]uint   `json:"categories"` 
+	Name        *string  `json:"name"`
+	Description *string  `json:"description"`
+	Price       *float64 `json:"price"`
+	Stock       *int     `json:"stock"`
+	Categories  *[]uint  `json:"categories"`
+	Reason      *string  `json:"reason"`
 }
 
 type ProductDTO struct {
@@ -37,7 +47,6 @@ type CategoryDTO struct {
 	Name string `json:"name"`
 }
 
-
 type ProductCategory struct {
 	ProductID  uint `gorm:"primaryKey"`
 	CategoryID uint `gorm:"primaryKey"`
@@ -1,12 +1,124 @@
 package models
 
-import "time"
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
 
 type ProductHistory struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	ProductID uint      `json:"product_id"`
-	Product   Product   `gorm:"foreignKey:ProductID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
-	Price     float64   `json:"price"`
-	Stock     int       `json:"stock"`
-	ChangedAt time.Time `json:"changed_at"`
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	ProductID      uint      `gorm:"index" json:"product_id"`
+	Product        Product   `gorm:"foreignKey:ProductID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	ChangeSetID    string    `gorm:"index" json:"change_set_id"`
+	Actor          string    `json:"actor"`
+	Reason         string    `json:"reason"`
+	OldName        string    `json:"old_name"`
+	NewName        string    `json:"new_name"`
+	OldDescription string    `json:"old_description"`
+	NewDescription string    `json:"new_description"`
+	OldPrice       float64   `gorm:"column:price" json:"old_price"`
+	NewPrice       float64   `json:"new_price"`
+	OldStock       int       `gorm:"column:stock" json:"old_stock"`
+	NewStock       int       `json:"new_stock"`
+	OldCategoryIDs IDList    `gorm:"type:text" json:"old_category_ids"`
+	NewCategoryIDs IDList    `gorm:"type:text" json:"new_category_ids"`
+	ChangedAt      time.Time `gorm:"index" json:"changed_at"`
+}
+
+// HasChanges indica si algun campo registrado difiere entre el estado
+// anterior y el nuevo.
+func (h *ProductHistory) HasChanges() bool {
+	return h.OldName != h.NewName ||
+		h.OldDescription != h.NewDescription ||
+		h.OldPrice != h.NewPrice ||
+		h.OldStock != h.NewStock ||
+		!h.OldCategoryIDs.Equal(h.NewCategoryIDs)
+}
+
+// IDList guarda una lista de IDs como JSON en una columna de texto.
+type IDList []uint
+
+func (l IDList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal([]uint(l))
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (l *IDList) Scan(value interface{}) error {
+	var raw []byte
+	switch v := value.(type) {
+	case nil:
+		*l = IDList{}
+		return nil
+	case string:
+		raw = []byte(v)
+	case []byte:
+		raw = v
+	default:
+		return fmt.Errorf("IDList: tipo no soportado %T", value)
+	}
+	if len(raw) == 0 {
+		*l = IDList{}
+		return nil
+	}
+	return json.Unmarshal(raw, (*[]uint)(l))
+}
+
+// Equal compara dos listas de IDs sin tener en cuenta el orden ni los duplicados.
+func (l IDList) Equal(other IDList) bool {
+	a, b := l.normalized(), other.normalized()
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (l IDList) normalized() []uint {
+	seen := make(map[uint]bool, len(l))
+	out := make([]uint, 0, len(l))
+	for _, id := range l {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+type HistoryBucketDTO struct {
+	Start      time.Time `json:"start"`
+	OpenPrice  float64   `json:"open_price"`
+	ClosePrice float64   `json:"close_price"`
+	MinPrice   float64   `json:"min_price"`
+	MaxPrice   float64   `json:"max_price"`
+	OpenStock  int       `json:"open_stock"`
+	CloseStock int       `json:"close_stock"`
+	MinStock   int       `json:"min_stock"`
+	MaxStock   int       `json:"max_stock"`
+	Changes    int       `json:"changes"`
+	Filled     bool      `json:"filled"`
+}
+
+type HistoryStatsDTO struct {
+	ProductID      uint               `json:"product_id"`
+	Interval       string             `json:"interval"`
+	Start          time.Time          `json:"start"`
+	End            time.Time          `json:"end"`
+	PriceChangePct *float64           `json:"price_change_pct"`
+	StockChangePct *float64           `json:"stock_change_pct"`
+	Buckets        []HistoryBucketDTO `json:"buckets"`
 }
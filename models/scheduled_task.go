@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+const (
+	TaskStatusRunning   = "running"
+	TaskStatusSucceeded = "succeeded"
+	TaskStatusFailed    = "failed"
+)
+
+// ScheduledTask guarda el estado de la ultima ejecucion de una tarea periodica.
+type ScheduledTask struct {
+	Name           string     `gorm:"primaryKey" json:"name"`
+	Schedule       string     `json:"schedule"`
+	LastStatus     string     `json:"last_status"`
+	LastError      string     `json:"last_error,omitempty"`
+	LastRunAt      *time.Time `json:"last_run_at"`
+	LastDurationMs int64      `json:"last_duration_ms"`
+	LastRunBy      string     `json:"last_run_by"`
+	NextRunAt      *time.Time `json:"next_run_at"`
+	RunCount       int64      `json:"run_count"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+type ScheduledPriceChange struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	ProductID   uint       `gorm:"index" json:"product_id"`
+	Product     Product    `gorm:"foreignKey:ProductID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	Price       float64    `json:"price"`
+	EffectiveAt time.Time  `json:"effective_at"`
+	Reason      string     `json:"reason"`
+	CreatedBy   string     `json:"created_by"`
+	AppliedAt   *time.Time `json:"applied_at"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type SchedulePriceChangeRequest struct {
+	Price       *float64   `json:"price"`
+	EffectiveAt *time.Time `json:"effective_at"`
+	Reason      string     `json:"reason"`
+}
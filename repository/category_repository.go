@@ -1,18 +1,19 @@
 package repository
 
 import (
-	"fmt"
+	"context"
+	"qisur-challenge/apperrors"
 	"qisur-challenge/models"
 
 	"gorm.io/gorm"
 )
 
 type CategoryRepository interface {
-	GetAll() ([]models.Category, error)
-	GetByID(id uint) (*models.Category, error)
-	Create(category *models.Category) error
-	Update(category *models.Category) error
-	Delete(category *models.Category) error
+	GetAll(ctx context.Context) ([]models.Category, error)
+	GetByID(ctx context.Context, id uint) (*models.Category, error)
+	Create(ctx context.Context, category *models.Category) error
+	Update(ctx context.Context, category *models.Category) error
+	Delete(ctx context.Context, category *models.Category) error
 }
 
 type categoryRepository struct {
@@ -23,47 +24,57 @@ func NewCategoryRepository(db *gorm.DB) CategoryRepository {
 	return &categoryRepository{db: db}
 }
 
-func (r *categoryRepository) GetAll() ([]models.Category, error) {
+func (r *categoryRepository) GetAll(ctx context.Context) ([]models.Category, error) {
 	var categories []models.Category
-	err := r.db.Preload("Products").Find(&categories).Error
+	err := reader(ctx, r.db).Preload("Products").Find(&categories).Error
 	return categories, err
 }
 
-func (r *categoryRepository) GetByID(id uint) (*models.Category, error) {
+func (r *categoryRepository) GetByID(ctx context.Context, id uint) (*models.Category, error) {
 	var category models.Category
-	if err := r.db.Preload("Products").First(&category, id).Error; err != nil {
-		return nil, err
+	if err := reader(ctx, r.db).Preload("Products").First(&category, id).Error; err != nil {
+		return nil, notFound(err, apperrors.CodeCategoryNotFound, "Categoría no encontrada")
 	}
 	return &category, nil
 }
 
-func (r *categoryRepository) Create(category *models.Category) error {
+func (r *categoryRepository) Create(ctx context.Context, category *models.Category) error {
 	var existingCategory models.Category
-	err := r.db.Where("name = ?", category.Name).First(&existingCategory).Error
+	err := r.db.WithContext(ctx).Where("name = ?", category.Name).First(&existingCategory).Error
 
 	if err == nil {
-		return fmt.Errorf("la categoria con nombre '%s' ya existe", category.Name)
+		return CategoryNameTakenError(category.Name)
 	}
 
-	return r.db.Create(category).Error
+	return r.db.WithContext(ctx).Create(category).Error
 }
 
-func (r *categoryRepository) Update(category *models.Category) error {
+func (r *categoryRepository) Update(ctx context.Context, category *models.Category) error {
 	var existingCategory models.Category
-	err := r.db.Where("name = ? AND id <> ?", category.Name, category.ID).First(&existingCategory).Error
+	err := r.db.WithContext(ctx).Where("name = ? AND id <> ?", category.Name, category.ID).First(&existingCategory).Error
 	if err == nil {
-		return fmt.Errorf("la categoria con nombre '%s' ya existe", category.Name)
+		return CategoryNameTakenError(category.Name)
 	}
 
-	if err := r.db.Preload("Products").First(&category, category.ID).Error; err != nil {
+	var current models.Category
+	if err := r.db.WithContext(ctx).First(&current, category.ID).Error; err != nil {
+		return notFound(err, apperrors.CodeCategoryNotFound, "Categoría no encontrada")
+	}
+	category.CreatedAt = current.CreatedAt
+	if err := r.db.WithContext(ctx).Omit("Products").Save(category).Error; err != nil {
 		return err
 	}
-	return r.db.Save(category).Error
+	return r.db.WithContext(ctx).Model(category).Association("Products").Find(&category.Products)
+}
+
+// CategoryNameTakenError es el error de un nombre de categoria repetido.
+func CategoryNameTakenError(name string) error {
+	return apperrors.Conflict(apperrors.CodeCategoryNameTaken, "la categoria con nombre '%s' ya existe", name).With("name", name)
 }
 
-func (r *categoryRepository) Delete(category *models.Category) error {
-	if err := r.db.Model(category).Association("Products").Clear(); err != nil {
+func (r *categoryRepository) Delete(ctx context.Context, category *models.Category) error {
+	if err := r.db.WithContext(ctx).Model(category).Association("Products").Clear(); err != nil {
 		return err
 	}
-	return r.db.Delete(category).Error
+	return r.db.WithContext(ctx).Delete(category).Error
 }
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"errors"
+
+	"qisur-challenge/apperrors"
+
+	"gorm.io/gorm"
+)
+
+// notFound convierte gorm.ErrRecordNotFound en un error de dominio con el
+// codigo y mensaje indicados; los demas errores se devuelven sin cambios. El
+// resultado sigue cumpliendo errors.Is(err, gorm.ErrRecordNotFound).
+func notFound(err error, code, message string) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperrors.NotFound(code, "%s", message).Wrap(err)
+	}
+	return err
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"qisur-challenge/dbdialect"
+	"qisur-challenge/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// HistoryRepository agrupa las operaciones de mantenimiento sobre la tabla de
+// historial completa, independientes de un producto en particular.
+type HistoryRepository interface {
+	CountBefore(ctx context.Context, t time.Time) (int64, error)
+	DeleteBefore(ctx context.Context, t time.Time) (int64, error)
+	EachBetween(ctx context.Context, start, end time.Time, fn func(history *models.ProductHistory) error) error
+	CountDownsample(ctx context.Context, start, end time.Time) (groups, entries int64, err error)
+	Update(ctx context.Context, history *models.ProductHistory) error
+	DeleteByIDs(ctx context.Context, ids []uint) error
+	Transaction(ctx context.Context, fn func(repo HistoryRepository) error) error
+}
+
+type historyRepository struct {
+	db *gorm.DB
+}
+
+func NewHistoryRepository(db *gorm.DB) HistoryRepository {
+	return &historyRepository{db: db}
+}
+
+func (r *historyRepository) CountBefore(ctx context.Context, t time.Time) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.ProductHistory{}).Where("changed_at < ?", t).Count(&count).Error
+	return count, err
+}
+
+func (r *historyRepository) DeleteBefore(ctx context.Context, t time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("changed_at < ?", t).Delete(&models.ProductHistory{})
+	return result.RowsAffected, result.Error
+}
+
+// EachBetween recorre las entradas con changed_at en [start, end) ordenadas
+// por producto y fecha, sin cargarlas todas en memoria.
+func (r *historyRepository) EachBetween(ctx context.Context, start, end time.Time, fn func(history *models.ProductHistory) error) error {
+	rows, err := r.db.WithContext(ctx).Model(&models.ProductHistory{}).
+		Where("changed_at >= ? AND changed_at < ?", start, end).
+		Order("product_id ASC").Order("changed_at ASC").Order("id ASC").
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var history models.ProductHistory
+		if err := r.db.ScanRows(rows, &history); err != nil {
+			return err
+		}
+		if err := fn(&history); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// CountDownsample cuenta, sin recorrer las entradas, cuantos grupos de
+// producto y dia en [start, end) tienen mas de una entrada y cuantas entradas
+// se eliminarian al dejar una por grupo.
+func (r *historyRepository) CountDownsample(ctx context.Context, start, end time.Time) (groups, entries int64, err error) {
+	day := dbdialect.DayUTC(r.db, "changed_at")
+	grouped := r.db.Model(&models.ProductHistory{}).
+		Select("COUNT(*) AS n").
+		Where("changed_at >= ? AND changed_at < ?", start, end).
+		Group("product_id, " + day).
+		Having("COUNT(*) > 1")
+
+	var result struct {
+		CompactedGroups int64
+		Entries         int64
+	}
+	err = r.db.WithContext(ctx).Table("(?) AS g", grouped).
+		Select("COUNT(*) AS compacted_groups, COALESCE(SUM(n - 1), 0) AS entries").
+		Scan(&result).Error
+	return result.CompactedGroups, result.Entries, err
+}
+
+func (r *historyRepository) Update(ctx context.Context, history *models.ProductHistory) error {
+	return r.db.WithContext(ctx).Omit("Product").Save(history).Error
+}
+
+func (r *historyRepository) DeleteByIDs(ctx context.Context, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Delete(&models.ProductHistory{}, ids).Error
+}
+
+func (r *historyRepository) Transaction(ctx context.Context, fn func(repo HistoryRepository) error) error {
+	return transaction(ctx, r.db, func(tx *gorm.DB) error {
+		return fn(&historyRepository{db: tx})
+	})
+}
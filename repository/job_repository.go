@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"qisur-challenge/apperrors"
+	"qisur-challenge/dbdialect"
+	"qisur-challenge/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type JobRepository interface {
+	Create(ctx context.Context, job *models.Job) error
+	GetByID(ctx context.Context, id uint) (*models.Job, error)
+	Claim(ctx context.Context, workerID string) (*models.Job, error)
+	// UpdateProgress, Heartbeat, Complete y Fail solo modifican el trabajo si
+	// sigue tomado por workerID; si no devuelven ErrJobLeaseLost.
+	UpdateProgress(ctx context.Context, id uint, workerID string, progress int) error
+	Heartbeat(ctx context.Context, id uint, workerID string) error
+	Complete(ctx context.Context, id uint, workerID string, result string) error
+	Fail(ctx context.Context, id uint, workerID string, errMsg string, retryAt *time.Time) error
+	RequeueStale(ctx context.Context, lockedBefore time.Time) (int64, error)
+}
+
+// ErrJobLeaseLost indica que el trabajo ya no esta tomado por el worker, por
+// ejemplo porque RequeueStale lo devolvio a la cola y lo tomo otro.
+var ErrJobLeaseLost = errors.New("el trabajo ya no está tomado por este worker")
+
+type jobRepository struct {
+	db *gorm.DB
+}
+
+func NewJobRepository(db *gorm.DB) JobRepository {
+	return &jobRepository{db: db}
+}
+
+func (r *jobRepository) Create(ctx context.Context, job *models.Job) error {
+	return r.db.WithContext(ctx).Create(job).Error
+}
+
+func (r *jobRepository) GetByID(ctx context.Context, id uint) (*models.Job, error) {
+	var job models.Job
+	if err := r.db.WithContext(ctx).First(&job, id).Error; err != nil {
+		return nil, notFound(err, apperrors.CodeJobNotFound, "Trabajo no encontrado")
+	}
+	return &job, nil
+}
+
+// Claim toma el proximo trabajo pendiente que todavia tenga intentos y lo
+// marca como en ejecucion. FOR UPDATE SKIP LOCKED permite que varios workers, incluso de distintas
+// replicas, reclamen trabajos sin bloquearse ni tomar el mismo.
+func (r *jobRepository) Claim(ctx context.Context, workerID string) (*models.Job, error) {
+	if dbdialect.IsSQLite(r.db) {
+		return r.claimSQLite(ctx, workerID)
+	}
+	var jobs []models.Job
+	err := r.db.WithContext(ctx).Raw(`
+		UPDATE jobs
+		SET status = ?, locked_by = ?, locked_at = now(), started_at = COALESCE(started_at, now()),
+		    attempts = attempts + 1, updated_at = now()
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE status = ? AND run_at <= now() AND attempts < max_attempts
+			ORDER BY run_at ASC, id ASC
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		RETURNING *`,
+		models.JobStatusRunning, workerID, models.JobStatusQueued,
+	).Scan(&jobs).Error
+	if err != nil || len(jobs) == 0 {
+		return nil, err
+	}
+	return &jobs[0], nil
+}
+
+// claimSQLite es Claim para SQLite, que no tiene FOR UPDATE ni now() pero
+// serializa las escrituras, asi que el UPDATE ya es atomico.
+func (r *jobRepository) claimSQLite(ctx context.Context, workerID string) (*models.Job, error) {
+	var jobs []models.Job
+	now := time.Now()
+	err := r.db.WithContext(ctx).Raw(`
+		UPDATE jobs
+		SET status = ?, locked_by = ?, locked_at = ?, started_at = COALESCE(started_at, ?),
+		    attempts = attempts + 1, updated_at = ?
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE status = ? AND run_at <= ? AND attempts < max_attempts
+			ORDER BY run_at ASC, id ASC
+			LIMIT 1
+		)
+		RETURNING *`,
+		models.JobStatusRunning, workerID, now, now, now, models.JobStatusQueued, now,
+	).Scan(&jobs).Error
+	if err != nil || len(jobs) == 0 {
+		return nil, err
+	}
+	return &jobs[0], nil
+}
+
+func (r *jobRepository) UpdateProgress(ctx context.Context, id uint, workerID string, progress int) error {
+	return r.leased(ctx, id, workerID, map[string]interface{}{
+		"progress":  progress,
+		"locked_at": time.Now(),
+	})
+}
+
+// Heartbeat renueva locked_at para que RequeueStale no considere abandonado un
+// trabajo que sigue en ejecucion.
+func (r *jobRepository) Heartbeat(ctx context.Context, id uint, workerID string) error {
+	return r.leased(ctx, id, workerID, map[string]interface{}{
+		"locked_at": time.Now(),
+	})
+}
+
+func (r *jobRepository) Complete(ctx context.Context, id uint, workerID string, result string) error {
+	now := time.Now()
+	return r.leased(ctx, id, workerID, map[string]interface{}{
+		"status":      models.JobStatusSucceeded,
+		"result":      result,
+		"error":       "",
+		"progress":    100,
+		"locked_by":   "",
+		"locked_at":   nil,
+		"finished_at": now,
+	})
+}
+
+// Fail registra el error del trabajo. Si retryAt no es nil el trabajo vuelve a
+// la cola para ese momento; si no, queda como fallido.
+func (r *jobRepository) Fail(ctx context.Context, id uint, workerID string, errMsg string, retryAt *time.Time) error {
+	updates := map[string]interface{}{
+		"error":     errMsg,
+		"locked_by": "",
+		"locked_at": nil,
+	}
+	if retryAt != nil {
+		updates["status"] = models.JobStatusQueued
+		updates["run_at"] = *retryAt
+	} else {
+		updates["status"] = models.JobStatusFailed
+		updates["finished_at"] = time.Now()
+	}
+	return r.leased(ctx, id, workerID, updates)
+}
+
+// leased aplica updates al trabajo solo si sigue en ejecucion y tomado por
+// workerID.
+func (r *jobRepository) leased(ctx context.Context, id uint, workerID string, updates map[string]interface{}) error {
+	result := r.db.WithContext(ctx).Model(&models.Job{}).
+		Where("id = ? AND status = ? AND locked_by = ?", id, models.JobStatusRunning, workerID).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrJobLeaseLost
+	}
+	return nil
+}
+
+// errStaleExhausted es el error que RequeueStale registra en los trabajos
+// abandonados que ya usaron todos sus intentos.
+const errStaleExhausted = "el worker dejó de responder y el trabajo no tiene más intentos"
+
+// RequeueStale devuelve a la cola los trabajos en ejecucion cuyo worker dejo
+// de reportar actividad, por ejemplo porque el proceso se detuvo. Los que ya
+// usaron todos sus intentos quedan como fallidos en lugar de volver a la cola.
+func (r *jobRepository) RequeueStale(ctx context.Context, lockedBefore time.Time) (int64, error) {
+	var requeued int64
+	err := transaction(ctx, r.db, func(tx *gorm.DB) error {
+		stale := func() *gorm.DB {
+			return tx.Model(&models.Job{}).Where("status = ? AND locked_at < ?", models.JobStatusRunning, lockedBefore)
+		}
+		now := time.Now()
+		err := stale().Where("attempts >= max_attempts").Updates(map[string]interface{}{
+			"status":      models.JobStatusFailed,
+			"error":       errStaleExhausted,
+			"locked_by":   "",
+			"locked_at":   nil,
+			"finished_at": now,
+		}).Error
+		if err != nil {
+			return err
+		}
+		result := stale().Updates(map[string]interface{}{
+			"status":    models.JobStatusQueued,
+			"locked_by": "",
+			"locked_at": nil,
+			"run_at":    now,
+		})
+		requeued = result.RowsAffected
+		return result.Error
+	})
+	return requeued, err
+}
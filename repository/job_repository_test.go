@@ -0,0 +1,96 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"qisur-challenge/config"
+	"qisur-challenge/migrations"
+	"qisur-challenge/models"
+	"qisur-challenge/repository"
+
+	"gorm.io/gorm"
+)
+
+func newDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	cfg := config.Default()
+	cfg.Database.Driver = "sqlite"
+	cfg.Database.Path = ":memory:"
+	config.AppConfig = cfg
+
+	db, err := config.CONNECTDB(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestRequeueStaleLastAttempt(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+	repo := repository.NewJobRepository(db)
+
+	last := &models.Job{Type: "export", Status: models.JobStatusQueued, MaxAttempts: 1, RunAt: time.Now()}
+	retry := &models.Job{Type: "export", Status: models.JobStatusQueued, MaxAttempts: 2, RunAt: time.Now()}
+	for _, job := range []*models.Job{last, retry} {
+		if err := repo.Create(ctx, job); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.Claim(ctx, "worker-caido"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// El worker deja de responder: ambos trabajos quedan abandonados.
+	requeued, err := repo.RequeueStale(ctx, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requeued != 1 {
+		t.Errorf("requeued = %d, se esperaba 1", requeued)
+	}
+
+	got, err := repo.GetByID(ctx, last.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != models.JobStatusFailed || got.Error == "" || got.FinishedAt == nil {
+		t.Errorf("trabajo en su ultimo intento = %+v, se esperaba fallido con error", got)
+	}
+
+	// Solo se vuelve a tomar el que tiene intentos disponibles.
+	claimed, err := repo.Claim(ctx, "worker-nuevo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claimed == nil || claimed.ID != retry.ID || claimed.Attempts != 2 {
+		t.Errorf("claim = %+v, se esperaba el trabajo %d en su segundo intento", claimed, retry.ID)
+	}
+	if claimed, err := repo.Claim(ctx, "worker-nuevo"); err != nil || claimed != nil {
+		t.Errorf("claim = %+v, %v; no deberia quedar trabajo pendiente", claimed, err)
+	}
+}
+
+func TestClaimSkipsExhaustedJobs(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+	repo := repository.NewJobRepository(db)
+
+	job := &models.Job{Type: "export", Status: models.JobStatusQueued, Attempts: 3, MaxAttempts: 3, RunAt: time.Now()}
+	if err := repo.Create(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+	if claimed, err := repo.Claim(ctx, "worker"); err != nil || claimed != nil {
+		t.Errorf("claim = %+v, %v; un trabajo sin intentos no se deberia tomar", claimed, err)
+	}
+}
@@ -0,0 +1,120 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"qisur-challenge/apperrors"
+	"qisur-challenge/models"
+	"qisur-challenge/repository"
+
+	"gorm.io/gorm"
+)
+
+type categoryRepository struct {
+	store *Store
+}
+
+func NewCategoryRepository(store *Store) repository.CategoryRepository {
+	return &categoryRepository{store: store}
+}
+
+func (r *categoryRepository) GetAll(ctx context.Context) ([]models.Category, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	categories := []models.Category{}
+	for _, c := range s.categories {
+		categories = append(categories, s.withProducts(c))
+	}
+	sort.Slice(categories, func(i, j int) bool { return categories[i].ID < categories[j].ID })
+	return categories, nil
+}
+
+func (r *categoryRepository) GetByID(ctx context.Context, id uint) (*models.Category, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.categories[id]
+	if !ok {
+		return nil, errCategoryNotFound()
+	}
+	c = s.withProducts(c)
+	return &c, nil
+}
+
+func (r *categoryRepository) Create(ctx context.Context, category *models.Category) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, c := range s.categories {
+		if c.Name == category.Name {
+			return repository.CategoryNameTakenError(category.Name)
+		}
+	}
+
+	now := time.Now()
+	if category.ID == 0 {
+		category.ID = s.nextID()
+	}
+	if category.CreatedAt.IsZero() {
+		category.CreatedAt = now
+	}
+	if category.UpdatedAt.IsZero() {
+		category.UpdatedAt = now
+	}
+	stored := *category
+	stored.Products = nil
+	s.categories[category.ID] = stored
+	return nil
+}
+
+func (r *categoryRepository) Update(ctx context.Context, category *models.Category) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, c := range s.categories {
+		if c.Name == category.Name && c.ID != category.ID {
+			return repository.CategoryNameTakenError(category.Name)
+		}
+	}
+	current, ok := s.categories[category.ID]
+	if !ok {
+		return errCategoryNotFound()
+	}
+
+	category.CreatedAt = current.CreatedAt
+	category.UpdatedAt = time.Now()
+	stored := *category
+	stored.Products = nil
+	s.categories[category.ID] = stored
+	category.Products = s.withProducts(stored).Products
+	return nil
+}
+
+func errCategoryNotFound() error {
+	return apperrors.NotFound(apperrors.CodeCategoryNotFound, "Categoría no encontrada").Wrap(gorm.ErrRecordNotFound)
+}
+
+func (r *categoryRepository) Delete(ctx context.Context, category *models.Category) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for productID, ids := range s.links {
+		kept := ids[:0]
+		for _, id := range ids {
+			if id != category.ID {
+				kept = append(kept, id)
+			}
+		}
+		s.links[productID] = kept
+	}
+	delete(s.categories, category.ID)
+	return nil
+}
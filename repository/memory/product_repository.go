@@ -0,0 +1,347 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"qisur-challenge/apperrors"
+	"qisur-challenge/models"
+	"qisur-challenge/repository"
+
+	"gorm.io/gorm"
+)
+
+type productRepository struct {
+	store *Store
+	inTx  bool
+}
+
+func NewProductRepository(store *Store) repository.ProductRepository {
+	return &productRepository{store: store}
+}
+
+func (r *productRepository) GetAll(ctx context.Context) ([]models.Product, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	products := []models.Product{}
+	for _, p := range s.products {
+		products = append(products, s.withCategories(p))
+	}
+	sortProductsByID(products)
+	return products, nil
+}
+
+func (r *productRepository) GetByID(ctx context.Context, id uint) (*models.Product, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.products[id]
+	if !ok {
+		return nil, apperrors.NotFound(apperrors.CodeProductNotFound, "Producto no encontrado").Wrap(gorm.ErrRecordNotFound)
+	}
+	p = s.withCategories(p)
+	return &p, nil
+}
+
+func (r *productRepository) GetByName(ctx context.Context, name string) (*models.Product, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, p := range s.products {
+		if p.Name == name {
+			p = s.withCategories(p)
+			return &p, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// Create guarda el producto y sus categorias. Como GORM, las categorias sin ID
+// o que no existen se crean y las existentes solo se asocian.
+func (r *productRepository) Create(ctx context.Context, product *models.Product) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, p := range s.products {
+		if p.Name == product.Name {
+			return repository.ProductNameTakenError(product.Name)
+		}
+	}
+
+	now := time.Now()
+	if product.ID == 0 {
+		product.ID = s.nextID()
+	}
+	if product.CreatedAt.IsZero() {
+		product.CreatedAt = now
+	}
+	if product.UpdatedAt.IsZero() {
+		product.UpdatedAt = now
+	}
+
+	var ids []uint
+	for i := range product.Categories {
+		c := &product.Categories[i]
+		if _, ok := s.categories[c.ID]; !ok {
+			if c.ID == 0 {
+				c.ID = s.nextID()
+			}
+			if c.CreatedAt.IsZero() {
+				c.CreatedAt = now
+			}
+			if c.UpdatedAt.IsZero() {
+				c.UpdatedAt = now
+			}
+			stored := *c
+			stored.Products = nil
+			s.categories[c.ID] = stored
+		}
+		ids = append(ids, c.ID)
+	}
+
+	stored := *product
+	stored.Categories = nil
+	s.products[product.ID] = stored
+	s.links[product.ID] = sortedIDs(ids)
+	return nil
+}
+
+func (r *productRepository) Update(ctx context.Context, product *models.Product) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, p := range s.products {
+		if p.Name == product.Name && p.ID != product.ID {
+			return repository.ProductNameTakenError(product.Name)
+		}
+	}
+
+	product.UpdatedAt = time.Now()
+	stored := *product
+	stored.Categories = nil
+	s.products[product.ID] = stored
+	return nil
+}
+
+func (r *productRepository) Delete(ctx context.Context, product *models.Product) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.links, product.ID)
+	delete(s.products, product.ID)
+	return nil
+}
+
+func (r *productRepository) UpdateCategories(ctx context.Context, product *models.Product, categoryIDs []uint) error {
+	categories, err := r.FindCategories(ctx, categoryIDs)
+	if err != nil {
+		return err
+	}
+
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]uint, len(categories))
+	for i, c := range categories {
+		ids[i] = c.ID
+	}
+	s.links[product.ID] = sortedIDs(ids)
+	product.Categories = categories
+	return nil
+}
+
+func (r *productRepository) FindCategories(ctx context.Context, categoryIDs []uint) ([]models.Category, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	categories := []models.Category{}
+	for _, id := range sortedIDs(categoryIDs) {
+		if c, ok := s.categories[id]; ok {
+			categories = append(categories, c)
+		}
+	}
+	return categories, nil
+}
+
+func (r *productRepository) FindCategoriesByName(ctx context.Context, names []string) ([]models.Category, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	categories := []models.Category{}
+	for _, c := range s.categories {
+		for _, name := range names {
+			if c.Name == name {
+				categories = append(categories, c)
+				break
+			}
+		}
+	}
+	sort.Slice(categories, func(i, j int) bool { return categories[i].ID < categories[j].ID })
+	return categories, nil
+}
+
+func (r *productRepository) SaveHistory(ctx context.Context, history *models.ProductHistory) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if history.ChangedAt.IsZero() {
+		history.ChangedAt = time.Now()
+	}
+	history.ID = s.nextID()
+	s.history = append(s.history, *history)
+	return nil
+}
+
+// historyWhere devuelve las entradas del producto que cumplen keep, ordenadas
+// por fecha y ID.
+func (r *productRepository) historyWhere(productID uint, keep func(h models.ProductHistory) bool) []models.ProductHistory {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	history := []models.ProductHistory{}
+	for _, h := range s.history {
+		if h.ProductID == productID && keep(h) {
+			history = append(history, h)
+		}
+	}
+	sort.SliceStable(history, func(i, j int) bool {
+		if !history[i].ChangedAt.Equal(history[j].ChangedAt) {
+			return history[i].ChangedAt.Before(history[j].ChangedAt)
+		}
+		return history[i].ID < history[j].ID
+	})
+	return history
+}
+
+func (r *productRepository) GetHistory(ctx context.Context, productID uint, start, end *time.Time) ([]models.ProductHistory, error) {
+	return r.historyWhere(productID, func(h models.ProductHistory) bool {
+		return (start == nil || !h.ChangedAt.Before(*start)) && (end == nil || h.ChangedAt.Before(*end))
+	}), nil
+}
+
+func (r *productRepository) GetHistoryByID(ctx context.Context, productID, historyID uint) (*models.ProductHistory, error) {
+	history := r.historyWhere(productID, func(h models.ProductHistory) bool { return h.ID == historyID })
+	if len(history) == 0 {
+		return nil, apperrors.NotFound(apperrors.CodeHistoryNotFound, "Entrada de historial no encontrada").Wrap(gorm.ErrRecordNotFound)
+	}
+	return &history[0], nil
+}
+
+func (r *productRepository) LastHistoryBefore(ctx context.Context, productID uint, at time.Time) (*models.ProductHistory, error) {
+	history := r.historyWhere(productID, func(h models.ProductHistory) bool { return !h.ChangedAt.After(at) })
+	if len(history) == 0 {
+		return nil, nil
+	}
+	return &history[len(history)-1], nil
+}
+
+func (r *productRepository) FirstHistoryAfter(ctx context.Context, productID uint, at time.Time) (*models.ProductHistory, error) {
+	history := r.historyWhere(productID, func(h models.ProductHistory) bool { return h.ChangedAt.After(at) })
+	if len(history) == 0 {
+		return nil, nil
+	}
+	return &history[0], nil
+}
+
+// filtered devuelve los productos que cumplen el filtro, sin categorias y en
+// el orden que pide filter.Sort o, si no pide ninguno, por ID.
+func (r *productRepository) filtered(filter repository.ProductFilter) []models.Product {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name := strings.ToLower(filter.Name)
+	products := []models.Product{}
+	for _, p := range s.products {
+		if strings.Contains(strings.ToLower(p.Name), name) {
+			products = append(products, p)
+		}
+	}
+	sortProductsByID(products)
+
+	switch filter.Sort {
+	case "price_asc":
+		sort.SliceStable(products, func(i, j int) bool { return products[i].Price < products[j].Price })
+	case "price_desc":
+		sort.SliceStable(products, func(i, j int) bool { return products[i].Price > products[j].Price })
+	}
+	return products
+}
+
+func (r *productRepository) Search(ctx context.Context, filter repository.ProductFilter, page, limit int) ([]models.Product, error) {
+	products := r.filtered(filter)
+	offset := (page - 1) * limit
+	if offset >= len(products) {
+		return []models.Product{}, nil
+	}
+	return products[offset:min(offset+limit, len(products))], nil
+}
+
+func (r *productRepository) GetLowStock(ctx context.Context, threshold int) ([]models.Product, error) {
+	products := []models.Product{}
+	for _, p := range r.filtered(repository.ProductFilter{}) {
+		if p.Stock <= threshold {
+			products = append(products, p)
+		}
+	}
+	sort.SliceStable(products, func(i, j int) bool { return products[i].Stock < products[j].Stock })
+	return products, nil
+}
+
+func (r *productRepository) Stream(ctx context.Context, filter repository.ProductFilter, batchSize int, fn func(batch []models.Product) error) error {
+	products := r.filtered(filter)
+
+	s := r.store
+	for start := 0; start < len(products); start += batchSize {
+		batch := products[start:min(start+batchSize, len(products))]
+		s.mu.Lock()
+		for i := range batch {
+			batch[i] = s.withCategories(batch[i])
+		}
+		s.mu.Unlock()
+		if err := fn(batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Transaction ejecuta fn de a una transaccion por vez y, si devuelve un error,
+// deshace todos los cambios que hizo.
+func (r *productRepository) Transaction(ctx context.Context, fn func(repo repository.ProductRepository) error) error {
+	if !r.inTx {
+		r.store.txMu.Lock()
+		defer r.store.txMu.Unlock()
+	}
+
+	snap := r.store.save()
+	if err := fn(&productRepository{store: r.store, inTx: true}); err != nil {
+		r.store.restore(snap)
+		return err
+	}
+	return nil
+}
+
+func sortProductsByID(products []models.Product) {
+	sort.Slice(products, func(i, j int) bool { return products[i].ID < products[j].ID })
+}
+
+func sortedIDs(ids []uint) []uint {
+	sorted := append([]uint(nil), ids...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted
+}
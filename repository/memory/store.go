@@ -0,0 +1,115 @@
+// Package memory implementa los repositorios de productos y categorias sobre
+// estructuras en memoria, con la misma semantica que los de GORM: devuelven
+// gorm.ErrRecordNotFound cuando no hay registro y rechazan nombres repetidos.
+// Se usa en los tests de servicios.
+package memory
+
+import (
+	"sync"
+
+	"qisur-challenge/models"
+)
+
+// Store guarda los datos compartidos por los repositorios en memoria. Los
+// repositorios creados sobre el mismo Store ven los mismos productos y
+// categorias.
+type Store struct {
+	mu         sync.Mutex
+	txMu       sync.Mutex
+	products   map[uint]models.Product
+	categories map[uint]models.Category
+	links      map[uint][]uint
+	history    []models.ProductHistory
+	lastID     uint
+}
+
+func NewStore() *Store {
+	return &Store{
+		products:   map[uint]models.Product{},
+		categories: map[uint]models.Category{},
+		links:      map[uint][]uint{},
+	}
+}
+
+// nextID devuelve un ID nuevo. Se comparte entre tablas, lo que no afecta a
+// quien solo compara IDs de un mismo tipo.
+func (s *Store) nextID() uint {
+	s.lastID++
+	return s.lastID
+}
+
+type snapshot struct {
+	products   map[uint]models.Product
+	categories map[uint]models.Category
+	links      map[uint][]uint
+	history    []models.ProductHistory
+	lastID     uint
+}
+
+// save copia el estado actual para poder deshacer una transaccion.
+func (s *Store) save() snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snap := snapshot{
+		products:   make(map[uint]models.Product, len(s.products)),
+		categories: make(map[uint]models.Category, len(s.categories)),
+		links:      make(map[uint][]uint, len(s.links)),
+		history:    append([]models.ProductHistory(nil), s.history...),
+		lastID:     s.lastID,
+	}
+	for id, p := range s.products {
+		snap.products[id] = p
+	}
+	for id, c := range s.categories {
+		snap.categories[id] = c
+	}
+	for id, l := range s.links {
+		snap.links[id] = append([]uint(nil), l...)
+	}
+	return snap
+}
+
+func (s *Store) restore(snap snapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.products = snap.products
+	s.categories = snap.categories
+	s.links = snap.links
+	s.history = snap.history
+	s.lastID = snap.lastID
+}
+
+// productCategories devuelve las categorias del producto ordenadas por ID,
+// con solo el ID y el nombre como las carga el repositorio de GORM.
+func (s *Store) productCategories(productID uint) []models.Category {
+	categories := []models.Category{}
+	for _, id := range s.links[productID] {
+		if c, ok := s.categories[id]; ok {
+			categories = append(categories, models.Category{ID: c.ID, Name: c.Name})
+		}
+	}
+	return categories
+}
+
+func (s *Store) withCategories(p models.Product) models.Product {
+	p.Categories = s.productCategories(p.ID)
+	return p
+}
+
+// withProducts devuelve la categoria con sus productos cargados, ordenados
+// por ID.
+func (s *Store) withProducts(c models.Category) models.Category {
+	c.Products = []models.Product{}
+	for productID, ids := range s.links {
+		for _, id := range ids {
+			if id == c.ID {
+				c.Products = append(c.Products, s.products[productID])
+				break
+			}
+		}
+	}
+	sortProductsByID(c.Products)
+	return c
+}
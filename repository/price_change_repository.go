@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"qisur-challenge/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PriceChangeRepository interface {
+	Create(ctx context.Context, change *models.ScheduledPriceChange) error
+	ListByProduct(ctx context.Context, productID uint) ([]models.ScheduledPriceChange, error)
+	Due(ctx context.Context, at time.Time) ([]models.ScheduledPriceChange, error)
+	MarkApplied(ctx context.Context, id uint, appliedAt time.Time, errMsg string) error
+}
+
+type priceChangeRepository struct {
+	db *gorm.DB
+}
+
+func NewPriceChangeRepository(db *gorm.DB) PriceChangeRepository {
+	return &priceChangeRepository{db: db}
+}
+
+func (r *priceChangeRepository) Create(ctx context.Context, change *models.ScheduledPriceChange) error {
+	return r.db.WithContext(ctx).Omit("Product").Create(change).Error
+}
+
+func (r *priceChangeRepository) ListByProduct(ctx context.Context, productID uint) ([]models.ScheduledPriceChange, error) {
+	var changes []models.ScheduledPriceChange
+	err := r.db.WithContext(ctx).Where("product_id = ?", productID).Order("effective_at ASC").Find(&changes).Error
+	return changes, err
+}
+
+// Due devuelve los cambios pendientes cuya fecha de vigencia ya paso, en orden
+// cronologico para que se apliquen en la secuencia prevista.
+func (r *priceChangeRepository) Due(ctx context.Context, at time.Time) ([]models.ScheduledPriceChange, error) {
+	var changes []models.ScheduledPriceChange
+	err := r.db.WithContext(ctx).Where("applied_at IS NULL AND effective_at <= ?", at).
+		Order("effective_at ASC").Order("id ASC").Find(&changes).Error
+	return changes, err
+}
+
+func (r *priceChangeRepository) MarkApplied(ctx context.Context, id uint, appliedAt time.Time, errMsg string) error {
+	return r.db.WithContext(ctx).Model(&models.ScheduledPriceChange{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"applied_at": appliedAt,
+		"error":      errMsg,
+	}).Error
+}
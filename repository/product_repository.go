@@ -1,22 +1,47 @@
 package repository
 
 import (
-	"fmt"
-	"log"
+	"context"
+	"errors"
+	"log/slog"
+	"qisur-challenge/apperrors"
+	"qisur-challenge/dbdialect"
+	"qisur-challenge/logger"
 	"qisur-challenge/models"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// ProductRepository accede a los productos. La funcion que recibe Transaction
+// puede ejecutarse mas de una vez si la transaccion falla por un error
+// transitorio.
 type ProductRepository interface {
-	GetAll() ([]models.Product, error)
-	GetByID(id uint) (*models.Product, error)
-	Create(product *models.Product) error
-	Update(product *models.Product) error
-	Delete(product *models.Product) error
-	SaveHistory(product *models.Product) error
-	UpdateCategories(product *models.Product, categoryIDs []uint) error
+	GetAll(ctx context.Context) ([]models.Product, error)
+	GetByID(ctx context.Context, id uint) (*models.Product, error)
+	GetByName(ctx context.Context, name string) (*models.Product, error)
+	Create(ctx context.Context, product *models.Product) error
+	Update(ctx context.Context, product *models.Product) error
+	Delete(ctx context.Context, product *models.Product) error
+	SaveHistory(ctx context.Context, history *models.ProductHistory) error
+	// GetHistory devuelve el historial con changed_at en [start, end).
+	GetHistory(ctx context.Context, productID uint, start, end *time.Time) ([]models.ProductHistory, error)
+	GetHistoryByID(ctx context.Context, productID, historyID uint) (*models.ProductHistory, error)
+	LastHistoryBefore(ctx context.Context, productID uint, at time.Time) (*models.ProductHistory, error)
+	FirstHistoryAfter(ctx context.Context, productID uint, at time.Time) (*models.ProductHistory, error)
+	FindCategories(ctx context.Context, categoryIDs []uint) ([]models.Category, error)
+	FindCategoriesByName(ctx context.Context, names []string) ([]models.Category, error)
+	UpdateCategories(ctx context.Context, product *models.Product, categoryIDs []uint) error
+	Search(ctx context.Context, filter ProductFilter, page, limit int) ([]models.Product, error)
+	GetLowStock(ctx context.Context, threshold int) ([]models.Product, error)
+	Stream(ctx context.Context, filter ProductFilter, batchSize int, fn func(batch []models.Product) error) error
+	Transaction(ctx context.Context, fn func(repo ProductRepository) error) error
+}
+
+// ProductFilter contiene los filtros comunes a la busqueda y la exportacion.
+type ProductFilter struct {
+	Name string
+	Sort string
 }
 
 type productRepository struct {
@@ -27,71 +52,257 @@ func NewProductRepository(db *gorm.DB) ProductRepository {
 	return &productRepository{db: db}
 }
 
-func (r *productRepository) GetAll() ([]models.Product, error) {
+func (r *productRepository) GetAll(ctx context.Context) ([]models.Product, error) {
 	var products []models.Product
-	err := r.db.Preload("Categories").Find(&products).Error
+	err := reader(ctx, r.db).Preload("Categories").Find(&products).Error
 	if err != nil {
-		log.Printf("ERROR: Falló al obtener productos: %v", err)
+		slog.Error("Falló al obtener productos", logger.Err(err))
 		return nil, err
 	}
 	return products, nil
 }
 
-func (r *productRepository) GetByID(id uint) (*models.Product, error) {
+func (r *productRepository) GetByID(ctx context.Context, id uint) (*models.Product, error) {
 	var product models.Product
-	if err := r.db.Preload("Categories", func(db *gorm.DB) *gorm.DB {
+	if err := reader(ctx, r.db).Preload("Categories", func(db *gorm.DB) *gorm.DB {
 		return db.Select("id", "name")
 	}).First(&product, id).Error; err != nil {
-		log.Printf("ERROR: Falló al obtener producto con ID %d: %v", id, err)
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			slog.Error("Falló al obtener producto", "product_id", id, logger.Err(err))
+		}
+		return nil, notFound(err, apperrors.CodeProductNotFound, "Producto no encontrado")
+	}
+	return &product, nil
+}
+
+func (r *productRepository) GetByName(ctx context.Context, name string) (*models.Product, error) {
+	var product models.Product
+	if err := reader(ctx, r.db).Preload("Categories", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "name")
+	}).Where("name = ?", name).First(&product).Error; err != nil {
 		return nil, err
 	}
 	return &product, nil
 }
 
-func (r *productRepository) Create(product *models.Product) error {
+func (r *productRepository) Create(ctx context.Context, product *models.Product) error {
 	var existingProduct models.Product
-	err := r.db.Where("name = ?", product.Name).First(&existingProduct).Error
+	err := r.db.WithContext(ctx).Where("name = ?", product.Name).First(&existingProduct).Error
 
 	if err == nil {
-		return fmt.Errorf("producto con nombre '%s' ya existe", product.Name)
+		return ProductNameTakenError(product.Name)
 	}
 
-	return r.db.Create(product).Error
+	return r.db.WithContext(ctx).Create(product).Error
 }
 
-func (r *productRepository) Update(product *models.Product) error {
+func (r *productRepository) Update(ctx context.Context, product *models.Product) error {
 	var existingProduct models.Product
-	err := r.db.Where("name = ? AND id <> ?", product.Name, product.ID).First(&existingProduct).Error
+	err := r.db.WithContext(ctx).Where("name = ? AND id <> ?", product.Name, product.ID).First(&existingProduct).Error
 	if err == nil {
-		return fmt.Errorf("producto con nombre '%s' ya existe", product.Name)
+		return ProductNameTakenError(product.Name)
 	}
-	return r.db.Save(product).Error
+	return r.db.WithContext(ctx).Save(product).Error
 }
 
-func (r *productRepository) Delete(product *models.Product) error {
-	if err := r.db.Model(product).Association("Categories").Clear(); err != nil {
-		log.Printf("error al desasociar categorías: %v", err)
+// ProductNameTakenError es el error de un nombre de producto repetido.
+func ProductNameTakenError(name string) error {
+	return apperrors.Conflict(apperrors.CodeProductNameTaken, "producto con nombre '%s' ya existe", name).With("name", name)
+}
+
+func (r *productRepository) Delete(ctx context.Context, product *models.Product) error {
+	if err := r.db.WithContext(ctx).Model(product).Association("Categories").Clear(); err != nil {
+		slog.Error("Error al desasociar categorías", "product_id", product.ID, logger.Err(err))
 		return err
 	}
-	return r.db.Delete(product).Error
+	return r.db.WithContext(ctx).Delete(product).Error
+}
+
+func (r *productRepository) UpdateCategories(ctx context.Context, product *models.Product, categoryIDs []uint) error {
+	categories, err := r.FindCategories(ctx, categoryIDs)
+	if err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Model(product).Association("Categories").Replace(&categories)
+}
+
+func (r *productRepository) FindCategories(ctx context.Context, categoryIDs []uint) ([]models.Category, error) {
+	categories := []models.Category{}
+	if len(categoryIDs) == 0 {
+		return categories, nil
+	}
+	err := reader(ctx, r.db).Where("id IN ?", categoryIDs).Find(&categories).Error
+	return categories, err
+}
+
+func (r *productRepository) SaveHistory(ctx context.Context, history *models.ProductHistory) error {
+	if history.ChangedAt.IsZero() {
+		history.ChangedAt = time.Now()
+	}
+	return r.db.WithContext(ctx).Create(history).Error
+}
+
+func (r *productRepository) FindCategoriesByName(ctx context.Context, names []string) ([]models.Category, error) {
+	categories := []models.Category{}
+	if len(names) == 0 {
+		return categories, nil
+	}
+	err := reader(ctx, r.db).Where("name IN ?", names).Find(&categories).Error
+	return categories, err
+}
+
+func (r *productRepository) GetHistory(ctx context.Context, productID uint, start, end *time.Time) ([]models.ProductHistory, error) {
+	var history []models.ProductHistory
+	query := reader(ctx, r.db).Where("product_id = ?", productID)
+
+	if start != nil {
+		query = query.Where("changed_at >= ?", *start)
+	}
+	if end != nil {
+		query = query.Where("changed_at < ?", *end)
+	}
+
+	err := query.Order("changed_at ASC").Order("id ASC").Find(&history).Error
+	return history, err
+}
+
+func (r *productRepository) GetHistoryByID(ctx context.Context, productID, historyID uint) (*models.ProductHistory, error) {
+	var history models.ProductHistory
+	if err := reader(ctx, r.db).Where("product_id = ?", productID).First(&history, historyID).Error; err != nil {
+		return nil, notFound(err, apperrors.CodeHistoryNotFound, "Entrada de historial no encontrada")
+	}
+	return &history, nil
+}
+
+// LastHistoryBefore devuelve la ultima modificacion registrada hasta el
+// instante indicado, o nil si no hay ninguna.
+func (r *productRepository) LastHistoryBefore(ctx context.Context, productID uint, at time.Time) (*models.ProductHistory, error) {
+	var history []models.ProductHistory
+	err := reader(ctx, r.db).Where("product_id = ? AND changed_at <= ?", productID, at).
+		Order("changed_at DESC").Order("id DESC").Limit(1).Find(&history).Error
+	if err != nil || len(history) == 0 {
+		return nil, err
+	}
+	return &history[0], nil
+}
+
+// FirstHistoryAfter devuelve la primera modificacion registrada despues del
+// instante indicado, o nil si no hay ninguna.
+func (r *productRepository) FirstHistoryAfter(ctx context.Context, productID uint, at time.Time) (*models.ProductHistory, error) {
+	var history []models.ProductHistory
+	err := reader(ctx, r.db).Where("product_id = ? AND changed_at > ?", productID, at).
+		Order("changed_at ASC").Order("id ASC").Limit(1).Find(&history).Error
+	if err != nil || len(history) == 0 {
+		return nil, err
+	}
+	return &history[0], nil
+}
+
+func (r *productRepository) filtered(ctx context.Context, filter ProductFilter) *gorm.DB {
+	db := reader(ctx, r.db).Model(&models.Product{})
+
+	if filter.Name != "" {
+		db = db.Where(dbdialect.ContainsFold(db, "name"), "%"+filter.Name+"%")
+	}
+
+	switch filter.Sort {
+	case "price_asc":
+		db = db.Order("price ASC")
+	case "price_desc":
+		db = db.Order("price DESC")
+	}
+	return db
+}
+
+func (r *productRepository) Search(ctx context.Context, filter ProductFilter, page, limit int) ([]models.Product, error) {
+	offset := (page - 1) * limit
+	var products []models.Product
+	err := r.filtered(ctx, filter).Offset(offset).Limit(limit).Find(&products).Error
+	return products, err
+}
+
+func (r *productRepository) GetLowStock(ctx context.Context, threshold int) ([]models.Product, error) {
+	var products []models.Product
+	err := reader(ctx, r.db).Where("stock <= ?", threshold).Order("stock ASC").Order("id ASC").Find(&products).Error
+	return products, err
 }
 
-func (r *productRepository) UpdateCategories(product *models.Product, categoryIDs []uint) error {
-	var categories []models.Category
-	if len(categoryIDs) > 0 {
-		if err := r.db.Where("id IN ?", categoryIDs).Find(&categories).Error; err != nil {
+// Stream recorre los productos que cumplen el filtro y los entrega en lotes de
+// batchSize, con sus categorias cargadas, para no tener todo el catalogo en
+// memoria. Cada lote es una consulta paginada por clave (orden y id del ultimo
+// producto) que termina antes de cargar sus categorias, por lo que el recorrido
+// usa una sola conexion a la vez.
+func (r *productRepository) Stream(ctx context.Context, filter ProductFilter, batchSize int, fn func(batch []models.Product) error) error {
+	var last *models.Product
+	for {
+		db := r.filtered(ctx, filter)
+		if last != nil {
+			db = afterProduct(db, filter.Sort, *last)
+		}
+		var batch []models.Product
+		if err := db.Order("id ASC").Limit(batchSize).Find(&batch).Error; err != nil {
 			return err
 		}
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := r.loadCategories(ctx, batch); err != nil {
+			return err
+		}
+		next := batch[len(batch)-1]
+		if err := fn(batch); err != nil {
+			return err
+		}
+		if len(batch) < batchSize {
+			return nil
+		}
+		last = &next
+	}
+}
+
+// afterProduct filtra los productos que van despues de last en el orden de
+// sort, desempatando por id.
+func afterProduct(db *gorm.DB, sort string, last models.Product) *gorm.DB {
+	switch sort {
+	case "price_asc":
+		return db.Where("price > ? OR (price = ? AND id > ?)", last.Price, last.Price, last.ID)
+	case "price_desc":
+		return db.Where("price < ? OR (price = ? AND id > ?)", last.Price, last.Price, last.ID)
 	}
-	return r.db.Model(product).Association("Categories").Replace(&categories)
+	return db.Where("id > ?", last.ID)
 }
 
-func (r *productRepository) SaveHistory(product *models.Product) error {
-	history := models.ProductHistory{
-		ProductID: product.ID,
-		Price:     product.Price,
-		Stock:     product.Stock,
-		ChangedAt: time.Now(),
+func (r *productRepository) loadCategories(ctx context.Context, products []models.Product) error {
+	ids := make([]uint, len(products))
+	index := make(map[uint]int, len(products))
+	for i, p := range products {
+		ids[i] = p.ID
+		index[p.ID] = i
+	}
+
+	var links []struct {
+		ProductID uint
+		ID        uint
+		Name      string
 	}
-	return r.db.Create(&history).Error
+	err := reader(ctx, r.db).Table("product_categories").
+		Select("product_categories.product_id, categories.id, categories.name").
+		Joins("JOIN categories ON categories.id = product_categories.category_id").
+		Where("product_categories.product_id IN ?", ids).
+		Order("categories.id ASC").
+		Scan(&links).Error
+	if err != nil {
+		return err
+	}
+	for _, l := range links {
+		p := &products[index[l.ProductID]]
+		p.Categories = append(p.Categories, models.Category{ID: l.ID, Name: l.Name})
+	}
+	return nil
+}
+
+func (r *productRepository) Transaction(ctx context.Context, fn func(repo ProductRepository) error) error {
+	return transaction(ctx, r.db, func(tx *gorm.DB) error {
+		return fn(&productRepository{db: tx})
+	})
 }
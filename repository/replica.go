@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+
+	"qisur-challenge/dbreplica"
+
+	"gorm.io/gorm"
+)
+
+// reader devuelve una sesion para consultas de solo lectura, que pueden ir a
+// una replica si hay replicas configuradas.
+func reader(ctx context.Context, db *gorm.DB) *gorm.DB {
+	return dbreplica.Read(db.WithContext(ctx))
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"qisur-challenge/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TaskRepository interface {
+	Register(ctx context.Context, name, schedule string) (*models.ScheduledTask, error)
+	SetNextRun(ctx context.Context, name string, next time.Time) error
+	RecordRun(ctx context.Context, name, runBy string, startedAt time.Time, runErr error, next time.Time) error
+	List(ctx context.Context) ([]models.ScheduledTask, error)
+}
+
+type taskRepository struct {
+	db *gorm.DB
+}
+
+func NewTaskRepository(db *gorm.DB) TaskRepository {
+	return &taskRepository{db: db}
+}
+
+// Register crea la tarea si no existe y actualiza su expresion, devolviendo el
+// estado guardado para conocer la ultima ejecucion.
+func (r *taskRepository) Register(ctx context.Context, name, schedule string) (*models.ScheduledTask, error) {
+	task := models.ScheduledTask{Name: name, Schedule: schedule}
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"schedule", "updated_at"}),
+	}).Create(&task).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := r.db.WithContext(ctx).First(&task, "name = ?", name).Error; err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+func (r *taskRepository) SetNextRun(ctx context.Context, name string, next time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.ScheduledTask{}).Where("name = ?", name).Update("next_run_at", next).Error
+}
+
+func (r *taskRepository) RecordRun(ctx context.Context, name, runBy string, startedAt time.Time, runErr error, next time.Time) error {
+	updates := map[string]interface{}{
+		"last_status":      models.TaskStatusSucceeded,
+		"last_error":       "",
+		"last_run_at":      startedAt,
+		"last_duration_ms": time.Since(startedAt).Milliseconds(),
+		"last_run_by":      runBy,
+		"next_run_at":      next,
+		"run_count":        gorm.Expr("run_count + 1"),
+	}
+	if runErr != nil {
+		updates["last_status"] = models.TaskStatusFailed
+		updates["last_error"] = runErr.Error()
+	}
+	return r.db.WithContext(ctx).Model(&models.ScheduledTask{}).Where("name = ?", name).Updates(updates).Error
+}
+
+func (r *taskRepository) List(ctx context.Context) ([]models.ScheduledTask, error) {
+	var tasks []models.ScheduledTask
+	err := r.db.WithContext(ctx).Order("name ASC").Find(&tasks).Error
+	return tasks, err
+}
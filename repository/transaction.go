@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+
+	"qisur-challenge/dbretry"
+
+	"gorm.io/gorm"
+)
+
+// transaction ejecuta fn en una transaccion y, si falla por un error
+// transitorio (por ejemplo un conflicto de serializacion), la repite completa.
+// Tambien la repite si se perdio la conexion antes del COMMIT, porque la base
+// descarta la transaccion; durante el COMMIT no se sabe si se aplico y no se
+// repite. fn debe poder ejecutarse mas de una vez. Dentro de otra transaccion
+// no se reintenta porque la externa ya quedo abortada y es ella la que se
+// repite.
+func transaction(ctx context.Context, db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	if _, inTx := db.Statement.ConnPool.(gorm.TxCommitter); inTx {
+		return db.WithContext(ctx).Transaction(fn)
+	}
+	var committing bool
+	retryable := func(err error) bool {
+		return dbretry.IsTransient(err) || (!committing && dbretry.IsConnectionLost(err))
+	}
+	return dbretry.Retry(ctx, retryable, func() error {
+		committing = false
+		return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := fn(tx); err != nil {
+				return err
+			}
+			committing = true
+			return nil
+		})
+	})
+}
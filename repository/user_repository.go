@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"qisur-challenge/apperrors"
+	"qisur-challenge/models"
+
+	"gorm.io/gorm"
+)
+
+type UserRepository interface {
+	GetByUsername(ctx context.Context, username string) (*models.User, error)
+	Create(ctx context.Context, user *models.User) error
+	Update(ctx context.Context, user *models.User) error
+	Count(ctx context.Context) (int64, error)
+}
+
+type userRepository struct {
+	db *gorm.DB
+}
+
+func NewUserRepository(db *gorm.DB) UserRepository {
+	return &userRepository{db: db}
+}
+
+func (r *userRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	var user models.User
+	if err := r.db.WithContext(ctx).Where("username = ?", username).First(&user).Error; err != nil {
+		return nil, notFound(err, apperrors.CodeUserNotFound, "Usuario no encontrado")
+	}
+	return &user, nil
+}
+
+func (r *userRepository) Create(ctx context.Context, user *models.User) error {
+	var existingUser models.User
+	err := r.db.WithContext(ctx).Where("username = ?", user.Username).First(&existingUser).Error
+
+	if err == nil {
+		return apperrors.Conflict(apperrors.CodeUserExists, "el usuario '%s' ya existe", user.Username).With("username", user.Username)
+	}
+
+	return r.db.WithContext(ctx).Create(user).Error
+}
+
+func (r *userRepository) Update(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Save(user).Error
+}
+
+func (r *userRepository) Count(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.User{}).Count(&count).Error
+	return count, err
+}
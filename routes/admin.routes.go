@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"qisur-challenge/controllers"
+	"qisur-challenge/scheduler"
+	"qisur-challenge/services"
+
+	"github.com/gorilla/mux"
+)
+
+func AdminRoutes(api *mux.Router, retentionService services.HistoryRetentionService, sched *scheduler.Scheduler) {
+	adminController := controllers.NewAdminController(retentionService, sched)
+
+	//rutas protegidas, solo para administradores
+	ApplyAdminRoute(api, "/admin/history/retention", adminController.GetHistoryRetention, "GET")
+	ApplyAdminRoute(api, "/admin/tasks", adminController.GetTasks, "GET")
+}
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"qisur-challenge/controllers"
+	"qisur-challenge/health"
+
+	"github.com/gorilla/mux"
+)
+
+// HealthRoutes registra los endpoints para el orquestador. No requieren token.
+func HealthRoutes(r *mux.Router, checker *health.Checker) {
+	healthController := controllers.NewHealthController(checker)
+
+	r.HandleFunc("/healthz", healthController.Healthz).Methods("GET")
+	r.HandleFunc("/readyz", healthController.Readyz).Methods("GET")
+}
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"qisur-challenge/controllers"
+	"qisur-challenge/jobs"
+
+	"github.com/gorilla/mux"
+)
+
+func JobRoutes(api *mux.Router, jobQueue jobs.Queue, exportDir string) {
+	jobController := controllers.NewJobController(jobQueue, exportDir)
+
+	//rutas protegidas
+	ApplyMiddlewareRoute(api, "/jobs/{id}", jobController.GetJob, "GET")
+	ApplyMiddlewareRoute(api, "/jobs/{id}/result", jobController.GetJobResult, "GET")
+}
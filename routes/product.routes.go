@@ -2,24 +2,31 @@ package routes
 
 import (
 	"qisur-challenge/controllers"
+	"qisur-challenge/jobs"
 	"qisur-challenge/services"
 
 	"github.com/gorilla/mux"
 	"gorm.io/gorm"
 )
 
-func ProductRoutes(db *gorm.DB, api *mux.Router) {
+func ProductRoutes(db *gorm.DB, api *mux.Router, jobQueue jobs.Queue) {
 	productService := services.NewProductService(db)
-	productController := controllers.NewProductController(db, productService)
+	productController := controllers.NewProductController(db, productService, jobQueue)
 	//rutas publicas
 	api.HandleFunc("/products", productController.GetProducts).Methods("GET")
 	api.HandleFunc("/search", productController.SearchHandler).Methods("GET")
 
 	//rutas protegidas
+	ApplyMiddlewareRoute(api, "/products/export", productController.ExportProducts, "GET")
 	ApplyMiddlewareRoute(api, "/products/{id}", productController.GetProduct, "GET")
 	ApplyMiddlewareRoute(api, "/products", productController.CreateProduct, "POST")
+	ApplyMiddlewareRoute(api, "/products/import", productController.ImportProducts, "POST")
 	ApplyMiddlewareRoute(api, "/products/{id}", productController.UpdateProduct, "PUT")
 	ApplyMiddlewareRoute(api, "/products/{id}", productController.DeleteProduct, "DELETE")
 	ApplyMiddlewareRoute(api, "/products/{id}/history", productController.GetProductHistory, "GET")
+	ApplyMiddlewareRoute(api, "/products/{id}/history/stats", productController.GetProductHistoryStats, "GET")
+	ApplyMiddlewareRoute(api, "/products/{id}/revert", productController.RevertProduct, "POST")
+	ApplyMiddlewareRoute(api, "/products/{id}/price-changes", productController.GetScheduledPriceChanges, "GET")
+	ApplyMiddlewareRoute(api, "/products/{id}/price-changes", productController.SchedulePriceChange, "POST")
 
 }
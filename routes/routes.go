@@ -6,8 +6,15 @@ import (
 	"github.com/gorilla/mux"
 	"gorm.io/gorm"
 
+	"qisur-challenge/config"
 	"qisur-challenge/controllers"
+	"qisur-challenge/health"
+	"qisur-challenge/jobs"
+	"qisur-challenge/metrics"
 	"qisur-challenge/middlewares"
+	"qisur-challenge/models"
+	"qisur-challenge/scheduler"
+	"qisur-challenge/services"
 	ws "qisur-challenge/webSocket"
 )
 
@@ -15,16 +22,36 @@ func ApplyMiddlewareRoute(router *mux.Router, route string, handler http.Handler
 	router.Handle(route, middlewares.AuthMiddleware(handler)).Methods(methods...)
 }
 
-func RegisterRoutes(db *gorm.DB) *mux.Router {
-	r := mux.NewRouter()
+// ApplyAdminRoute registra una ruta que ademas de autenticacion requiere el
+// rol admin.
+func ApplyAdminRoute(router *mux.Router, route string, handler http.HandlerFunc, methods ...string) {
+	router.Handle(route, middlewares.AuthMiddleware(middlewares.RequireRole(models.RoleAdmin)(handler))).Methods(methods...)
+}
 
+func RegisterRoutes(db *gorm.DB, checker *health.Checker, retentionService services.HistoryRetentionService, sched *scheduler.Scheduler, jobQueue jobs.Queue, exportDir string) *mux.Router {
+	r := mux.NewRouter()
+	r.NotFoundHandler = middlewares.RequestIDMiddleware(middlewares.LanguageMiddleware(http.HandlerFunc(controllers.NotFound)))
+	r.MethodNotAllowedHandler = middlewares.RequestIDMiddleware(middlewares.LanguageMiddleware(http.HandlerFunc(controllers.MethodNotAllowed)))
+	r.Use(middlewares.RequestIDMiddleware)
+	r.Use(middlewares.LanguageMiddleware)
+	r.Use(middlewares.StartupMiddleware(checker, "/healthz", "/readyz", "/metrics"))
+	r.Use(middlewares.TracingMiddleware)
+	r.Use(middlewares.MetricsMiddleware)
+	r.Use(middlewares.MaxBodyMiddleware(config.AppConfig.Server.MaxBodyBytes, "/api/products/import"))
+	r.Use(middlewares.QueryTimeoutMiddleware(config.AppConfig.Database.QueryTimeout, "/api/products/import", "/api/products/export", "/ws"))
+	r.Use(middlewares.ReplicaSessionMiddleware)
+
+	HealthRoutes(r, checker)
+	r.Handle("/metrics", metrics.Handler()).Methods("GET")
 	r.HandleFunc("/api/login", controllers.Login(db)).Methods("POST")
-    r.Handle("/ws", middlewares.AuthMiddleware(http.HandlerFunc(ws.HandleWebSocket)))
+	r.Handle("/ws", middlewares.AuthMiddleware(http.HandlerFunc(ws.HandleWebSocket)))
 
 	api := r.PathPrefix("/api").Subrouter()
 
-	ProductRoutes(db, api)
+	ProductRoutes(db, api, jobQueue)
 	CategoriesRoutes(db, api)
+	AdminRoutes(api, retentionService, sched)
+	JobRoutes(api, jobQueue, exportDir)
 
 	return r
 }
@@ -0,0 +1,294 @@
+package routes_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"qisur-challenge/apperrors"
+	"qisur-challenge/config"
+	"qisur-challenge/health"
+	"qisur-challenge/jobs"
+	"qisur-challenge/migrations"
+	"qisur-challenge/models"
+	"qisur-challenge/routes"
+	"qisur-challenge/services"
+
+	"gorm.io/gorm"
+)
+
+// server es el router completo sobre una base SQLite en memoria, como lo arma
+// el comando serve.
+type server struct {
+	handler http.Handler
+	db      *gorm.DB
+	checker *health.Checker
+	token   string
+}
+
+func newServer(t *testing.T) *server {
+	t.Helper()
+	cfg := config.Default()
+	cfg.Database.Driver = "sqlite"
+	cfg.Database.Path = ":memory:"
+	cfg.Auth.JWTSecret = "secreto-de-prueba"
+	config.AppConfig = cfg
+
+	db, err := config.CONNECTDB(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	if _, err := migrations.Up(db); err != nil {
+		t.Fatal(err)
+	}
+
+	exportDir := t.TempDir()
+	runner := jobs.NewRunner(db, 0, time.Second)
+	jobs.RegisterProductHandlers(runner, services.NewProductService(db), exportDir)
+	retention := services.NewHistoryRetentionService(db, services.RetentionPolicy{})
+	checker := health.NewChecker(db)
+	checker.SetPhase(health.PhaseReady)
+
+	return &server{
+		handler: routes.RegisterRoutes(db, checker, retention, nil, runner, exportDir),
+		db:      db,
+		checker: checker,
+		token:   newToken(t, "admin", models.RoleAdmin),
+	}
+}
+
+func newToken(t *testing.T, username, role string) string {
+	t.Helper()
+	token, err := services.GenerateToken(username, role, "", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// do envia la request al router. Con auth se agrega el token del
+// administrador.
+func (s *server) do(method, target, body string, auth bool, headers ...string) *httptest.ResponseRecorder {
+	if auth {
+		headers = append(headers, "Authorization", "Bearer "+s.token)
+	}
+	return s.doWithToken("", method, target, body, headers...)
+}
+
+// doWithToken envia la request con el token indicado, o sin token si es "".
+func (s *server) doWithToken(token, method, target, body string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	rec := httptest.NewRecorder()
+	s.handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("respuesta no es JSON: %v (body: %s)", err, rec.Body.String())
+	}
+}
+
+func assertStatus(t *testing.T, rec *httptest.ResponseRecorder, want int) {
+	t.Helper()
+	if rec.Code != want {
+		t.Fatalf("status = %d, se esperaba %d (body: %s)", rec.Code, want, rec.Body.String())
+	}
+}
+
+func TestProductLifecycle(t *testing.T) {
+	s := newServer(t)
+
+	rec := s.do("POST", "/api/categories", `{"name":"Periféricos"}`, true)
+	assertStatus(t, rec, http.StatusOK)
+	var category models.Category
+	decode(t, rec, &category)
+
+	rec = s.do("POST", "/api/products", `{"name":"Mouse Inalámbrico","price":10,"stock":5,"categories":[{"id":`+strconv.FormatUint(uint64(category.ID), 10)+`}]}`, true)
+	assertStatus(t, rec, http.StatusOK)
+	var product models.Product
+	decode(t, rec, &product)
+	path := "/api/products/" + strconv.FormatUint(uint64(product.ID), 10)
+
+	rec = s.do("PUT", path, `{"price":12,"reason":"aumento"}`, true)
+	assertStatus(t, rec, http.StatusOK)
+	if got := rec.Header().Get("X-Product-Changed"); got != "true" {
+		t.Errorf("X-Product-Changed = %q, se esperaba true", got)
+	}
+
+	rec = s.do("GET", path+"/history", "", true)
+	assertStatus(t, rec, http.StatusOK)
+	var history []models.ProductHistory
+	decode(t, rec, &history)
+	if len(history) != 1 || history[0].OldPrice != 10 || history[0].NewPrice != 12 || history[0].Actor != "admin" {
+		t.Errorf("historial = %+v", history)
+	}
+
+	// La busqueda no distingue mayusculas tambien en SQLite.
+	rec = s.do("GET", "/api/search?type=product&name=MOUSE", "", false)
+	assertStatus(t, rec, http.StatusOK)
+	var found []models.Product
+	decode(t, rec, &found)
+	if len(found) != 1 || found[0].ID != product.ID {
+		t.Errorf("busqueda = %+v", found)
+	}
+
+	rec = s.do("GET", "/api/products/export?format=csv&columns=name,price,categories", "", true)
+	assertStatus(t, rec, http.StatusOK)
+	if want := "Mouse Inalámbrico,12,Periféricos"; !strings.Contains(rec.Body.String(), want) {
+		t.Errorf("export = %q, se esperaba una fila %q", rec.Body.String(), want)
+	}
+
+	rec = s.do("DELETE", path, "", true)
+	if rec.Code >= 300 {
+		t.Fatalf("delete: status = %d (body: %s)", rec.Code, rec.Body.String())
+	}
+	rec = s.do("GET", path, "", true)
+	assertStatus(t, rec, http.StatusNotFound)
+}
+
+func TestErrorResponses(t *testing.T) {
+	s := newServer(t)
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		auth        bool
+		headers     []string
+		wantStatus  int
+		wantCode    string
+		wantMessage string
+	}{
+		{name: "sin token", method: "POST", target: "/api/products", wantStatus: http.StatusUnauthorized, wantCode: apperrors.CodeUnauthorized},
+		{name: "producto inexistente", method: "GET", target: "/api/products/99", auth: true, wantStatus: http.StatusNotFound, wantCode: apperrors.CodeProductNotFound},
+		{name: "ruta inexistente", method: "GET", target: "/api/nada", wantStatus: http.StatusNotFound, wantCode: apperrors.CodeRouteNotFound},
+		{
+			name:        "mensaje en ingles",
+			method:      "GET",
+			target:      "/api/products/99",
+			auth:        true,
+			headers:     []string{"Accept-Language", "en"},
+			wantStatus:  http.StatusNotFound,
+			wantCode:    apperrors.CodeProductNotFound,
+			wantMessage: "Product not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := s.do(tt.method, tt.target, "", tt.auth, append([]string{"X-Request-ID", "prueba-1"}, tt.headers...)...)
+			assertStatus(t, rec, tt.wantStatus)
+			var body apperrors.Response
+			decode(t, rec, &body)
+			if body.Code != tt.wantCode || body.RequestID != "prueba-1" {
+				t.Errorf("body = %+v, se esperaba el codigo %q con request_id", body, tt.wantCode)
+			}
+			if tt.wantMessage != "" && body.Message != tt.wantMessage {
+				t.Errorf("message = %q, se esperaba %q", body.Message, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestStartup(t *testing.T) {
+	s := newServer(t)
+	s.checker.SetPhase(health.PhaseStarting)
+
+	assertStatus(t, s.do("GET", "/healthz", "", false), http.StatusOK)
+	assertStatus(t, s.do("GET", "/readyz", "", false), http.StatusServiceUnavailable)
+	rec := s.do("GET", "/api/products", "", false)
+	assertStatus(t, rec, http.StatusServiceUnavailable)
+	var body apperrors.Response
+	decode(t, rec, &body)
+	if body.Code != apperrors.CodeUnavailable {
+		t.Errorf("code = %q, se esperaba %q", body.Code, apperrors.CodeUnavailable)
+	}
+
+	s.checker.SetPhase(health.PhaseReady)
+	assertStatus(t, s.do("GET", "/readyz", "", false), http.StatusOK)
+	assertStatus(t, s.do("GET", "/api/products", "", false), http.StatusOK)
+}
+
+func TestCategoryUpdatePersists(t *testing.T) {
+	s := newServer(t)
+
+	rec := s.do("POST", "/api/categories", `{"name":"Audio","description":"vieja"}`, true)
+	assertStatus(t, rec, http.StatusOK)
+	var category models.Category
+	decode(t, rec, &category)
+	path := "/api/categories/" + strconv.FormatUint(uint64(category.ID), 10)
+
+	assertStatus(t, s.do("PUT", path, `{"name":"Sonido","description":"nueva"}`, true), http.StatusOK)
+
+	rec = s.do("GET", path, "", true)
+	assertStatus(t, rec, http.StatusOK)
+	var got models.Category
+	decode(t, rec, &got)
+	if got.Name != "Sonido" || got.Description != "nueva" {
+		t.Errorf("categoria = %+v, se esperaba el nombre y la descripcion nuevos", got)
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	s := newServer(t)
+	ana := newToken(t, "ana", models.RoleUser)
+	luis := newToken(t, "luis", models.RoleUser)
+
+	for _, target := range []string{"/api/admin/history/retention", "/api/admin/tasks"} {
+		rec := s.doWithToken(ana, "GET", target, "")
+		assertStatus(t, rec, http.StatusForbidden)
+		var body apperrors.Response
+		decode(t, rec, &body)
+		if body.Code != apperrors.CodeForbidden {
+			t.Errorf("%s: code = %q, se esperaba %q", target, body.Code, apperrors.CodeForbidden)
+		}
+	}
+	assertStatus(t, s.do("GET", "/api/admin/history/retention", "", true), http.StatusOK)
+
+	// Un trabajo solo lo consultan quien lo encolo y los administradores.
+	rec := s.doWithToken(ana, "GET", "/api/products/export?format=csv&async=true", "")
+	assertStatus(t, rec, http.StatusAccepted)
+	jobPath := rec.Header().Get("Location")
+	assertStatus(t, s.doWithToken(ana, "GET", jobPath, ""), http.StatusOK)
+	assertStatus(t, s.doWithToken(luis, "GET", jobPath, ""), http.StatusForbidden)
+	assertStatus(t, s.doWithToken(luis, "GET", jobPath+"/result", ""), http.StatusForbidden)
+	assertStatus(t, s.do("GET", jobPath, "", true), http.StatusOK)
+}
+
+func TestJobResultMissing(t *testing.T) {
+	s := newServer(t)
+
+	rec := s.do("GET", "/api/products/export?format=csv&async=true", "", true)
+	assertStatus(t, rec, http.StatusAccepted)
+	jobPath := rec.Header().Get("Location")
+	assertStatus(t, s.do("GET", jobPath+"/result", "", true), http.StatusConflict)
+
+	// El trabajo termino en otra replica: el archivo no esta en este servidor.
+	id := strings.TrimPrefix(jobPath, "/api/jobs/")
+	if err := s.db.Model(&models.Job{}).Where("id = ?", id).Update("status", models.JobStatusSucceeded).Error; err != nil {
+		t.Fatal(err)
+	}
+	rec = s.do("GET", jobPath+"/result", "", true)
+	assertStatus(t, rec, http.StatusNotFound)
+	var body apperrors.Response
+	decode(t, rec, &body)
+	if body.Code != apperrors.CodeJobResultMissing {
+		t.Errorf("code = %q, se esperaba %q", body.Code, apperrors.CodeJobResultMissing)
+	}
+}
@@ -0,0 +1,275 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"qisur-challenge/dbdialect"
+	"qisur-challenge/logger"
+	"qisur-challenge/models"
+	"qisur-challenge/repository"
+	"qisur-challenge/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// leaderLockKey identifica el advisory lock de Postgres que otorga el
+// liderazgo del scheduler. Solo la replica que lo tiene ejecuta las tareas.
+const leaderLockKey int64 = 7305912
+
+// tickInterval es cada cuanto se revisa el liderazgo y las tareas vencidas.
+const tickInterval = time.Second
+
+type TaskFunc func(ctx context.Context) error
+
+type task struct {
+	name     string
+	spec     string
+	schedule Schedule
+	fn       TaskFunc
+	next     time.Time
+	running  bool
+}
+
+type Scheduler struct {
+	db       *gorm.DB
+	repo     repository.TaskRepository
+	instance string
+
+	mu     sync.Mutex
+	tasks  []*task
+	leader bool
+	conn   *sql.Conn
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func New(db *gorm.DB) *Scheduler {
+	hostname, _ := os.Hostname()
+	return &Scheduler{
+		db:       db,
+		repo:     repository.NewTaskRepository(db),
+		instance: fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+	}
+}
+
+// Add registra una tarea con una expresion cron o "@every <duracion>". Debe
+// llamarse antes de Start.
+func (s *Scheduler) Add(name, spec string, fn TaskFunc) error {
+	schedule, err := Parse(spec)
+	if err != nil {
+		return fmt.Errorf("tarea %s: %w", name, err)
+	}
+	s.tasks = append(s.tasks, &task{name: name, spec: spec, schedule: schedule, fn: fn})
+	return nil
+}
+
+func (s *Scheduler) Start() error {
+	if s.cancel != nil {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	for _, t := range s.tasks {
+		if _, err := s.repo.Register(ctx, t.name, t.spec); err != nil {
+			cancel()
+			return err
+		}
+	}
+
+	s.cancel = cancel
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.loop(ctx)
+	}()
+	return nil
+}
+
+// Stop espera a que terminen las tareas en curso y libera el liderazgo.
+func (s *Scheduler) Stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	s.wg.Wait()
+	s.cancel = nil
+	s.releaseLeadership()
+}
+
+func (s *Scheduler) IsLeader() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.leader
+}
+
+func (s *Scheduler) Instance() string {
+	return s.instance
+}
+
+// Status devuelve el estado guardado de todas las tareas.
+func (s *Scheduler) Status(ctx context.Context) ([]models.ScheduledTask, error) {
+	return s.repo.List(ctx)
+}
+
+func (s *Scheduler) loop(ctx context.Context) {
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+
+	for {
+		if s.ensureLeadership(ctx) {
+			s.runDue(ctx, time.Now())
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ensureLeadership intenta tomar el advisory lock en una conexion dedicada, o
+// verifica que la conexion que lo tiene siga viva.
+func (s *Scheduler) ensureLeadership(ctx context.Context) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn != nil {
+		err := s.conn.PingContext(ctx)
+		if err == nil {
+			return true
+		}
+		slog.Warn("Scheduler: se perdió la conexión del líder", "instance", s.instance, logger.Err(err))
+		s.conn.Close()
+		s.conn = nil
+		s.leader = false
+	}
+
+	// SQLite no tiene advisory locks: la base es de un solo proceso, que
+	// siempre es el lider.
+	if dbdialect.IsSQLite(s.db) {
+		if !s.leader {
+			s.leader = true
+			slog.Info("Scheduler: esta instancia es la líder", "instance", s.instance)
+			s.planTasks(ctx)
+		}
+		return true
+	}
+
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return false
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return false
+	}
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", leaderLockKey).Scan(&acquired); err != nil || !acquired {
+		conn.Close()
+		return false
+	}
+
+	s.conn = conn
+	s.leader = true
+	slog.Info("Scheduler: esta instancia es la líder", "instance", s.instance)
+	s.planTasks(ctx)
+	return true
+}
+
+// planTasks calcula la proxima ejecucion de cada tarea a partir de la ultima
+// registrada, para que un cambio de lider no repita ni saltee ejecuciones.
+func (s *Scheduler) planTasks(ctx context.Context) {
+	now := time.Now()
+	states, err := s.repo.List(ctx)
+	if err != nil {
+		slog.Error("Scheduler: error al leer el estado de las tareas", logger.Err(err))
+	}
+	lastRun := map[string]time.Time{}
+	for _, st := range states {
+		if st.LastRunAt != nil {
+			lastRun[st.Name] = *st.LastRunAt
+		}
+	}
+
+	for _, t := range s.tasks {
+		from := now
+		if last, ok := lastRun[t.name]; ok {
+			from = last
+		}
+		t.next = t.schedule.Next(from)
+		if t.next.Before(now) {
+			t.next = now
+		}
+		if err := s.repo.SetNextRun(ctx, t.name, t.next); err != nil {
+			slog.Error("Scheduler: error al guardar la próxima ejecución", "task", t.name, logger.Err(err))
+		}
+	}
+}
+
+func (s *Scheduler) releaseLeadership() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return
+	}
+	s.conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", leaderLockKey)
+	s.conn.Close()
+	s.conn = nil
+	s.leader = false
+}
+
+func (s *Scheduler) runDue(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, t := range s.tasks {
+		if t.running || now.Before(t.next) {
+			continue
+		}
+		t.running = true
+		s.wg.Add(1)
+		go func(t *task) {
+			defer s.wg.Done()
+			s.execute(ctx, t)
+		}(t)
+	}
+}
+
+func (s *Scheduler) execute(ctx context.Context, t *task) {
+	ctx, span := tracing.Start(ctx, "task "+t.name, trace.WithAttributes(
+		attribute.String("task.schedule", t.spec),
+	))
+	startedAt := time.Now()
+	err := safeRun(ctx, t.fn)
+	tracing.End(span, err)
+	next := t.schedule.Next(time.Now())
+
+	if err != nil {
+		slog.Error("Scheduler: la tarea falló", "task", t.name, logger.Err(err))
+	}
+	if recErr := s.repo.RecordRun(context.WithoutCancel(ctx), t.name, s.instance, startedAt, err, next); recErr != nil {
+		slog.Error("Scheduler: error al registrar la ejecución", "task", t.name, logger.Err(recErr))
+	}
+
+	s.mu.Lock()
+	t.next = next
+	t.running = false
+	s.mu.Unlock()
+}
+
+func safeRun(ctx context.Context, fn TaskFunc) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("panic en la tarea: %v", rec)
+		}
+	}()
+	return fn(ctx)
+}
@@ -0,0 +1,294 @@
+package seed
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"qisur-challenge/models"
+	"qisur-challenge/services"
+
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+//go:embed fixtures/*.yaml
+var fixtures embed.FS
+
+// DefaultFixture es el archivo embebido que se carga cuando no se indica otro.
+const DefaultFixture = "fixtures/demo.yaml"
+
+type Fixture struct {
+	Categories []CategoryFixture `json:"categories" yaml:"categories"`
+	Products   []ProductFixture  `json:"products" yaml:"products"`
+}
+
+type CategoryFixture struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description" yaml:"description"`
+}
+
+// ProductFixture describe un producto y sus categorias por nombre. History
+// lista los valores sucesivos de precio y stock: la primera entrada es el
+// estado inicial y cada una de las siguientes se registra como un cambio.
+type ProductFixture struct {
+	Name        string           `json:"name" yaml:"name"`
+	Description string           `json:"description" yaml:"description"`
+	Price       float64          `json:"price" yaml:"price"`
+	Stock       int              `json:"stock" yaml:"stock"`
+	Categories  []string         `json:"categories" yaml:"categories"`
+	History     []HistoryFixture `json:"history" yaml:"history"`
+}
+
+type HistoryFixture struct {
+	ChangedAt time.Time `json:"changed_at" yaml:"changed_at"`
+	Price     float64   `json:"price" yaml:"price"`
+	Stock     int       `json:"stock" yaml:"stock"`
+	Actor     string    `json:"actor" yaml:"actor"`
+	Reason    string    `json:"reason" yaml:"reason"`
+}
+
+type Result struct {
+	CategoriesCreated int
+	CategoriesUpdated int
+	ProductsCreated   int
+	ProductsUpdated   int
+	HistoryCreated    int
+}
+
+// LoadFile lee un fixture YAML o JSON segun su extension. Las rutas que no
+// existen en disco se buscan entre los fixtures embebidos.
+func LoadFile(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		data, err = fixtures.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var fixture Fixture
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &fixture)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &fixture)
+	default:
+		return nil, fmt.Errorf("formato de fixture no soportado: %s", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fixture %s: %w", path, err)
+	}
+	return &fixture, nil
+}
+
+// Apply carga el fixture en una unica transaccion. Categorias y productos se
+// identifican por nombre, por lo que aplicar el mismo fixture varias veces no
+// genera duplicados.
+func Apply(db *gorm.DB, fixture *Fixture) (*Result, error) {
+	result := &Result{}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		categoryIDs := map[string]uint{}
+		for _, c := range fixture.Categories {
+			category, created, err := upsertCategory(tx, c)
+			if err != nil {
+				return err
+			}
+			if created {
+				result.CategoriesCreated++
+			} else {
+				result.CategoriesUpdated++
+			}
+			categoryIDs[category.Name] = category.ID
+		}
+
+		for _, p := range fixture.Products {
+			categories := make([]models.Category, 0, len(p.Categories))
+			for _, name := range p.Categories {
+				id, ok := categoryIDs[name]
+				if !ok {
+					var category models.Category
+					if err := tx.Where("name = ?", name).First(&category).Error; err != nil {
+						return fmt.Errorf("producto '%s': categoría '%s' no encontrada", p.Name, name)
+					}
+					id = category.ID
+					categoryIDs[name] = id
+				}
+				categories = append(categories, models.Category{ID: id})
+			}
+
+			product, created, err := upsertProduct(tx, p, categories)
+			if err != nil {
+				return err
+			}
+			if created {
+				result.ProductsCreated++
+			} else {
+				result.ProductsUpdated++
+			}
+
+			count, err := insertHistory(tx, product, p.History)
+			if err != nil {
+				return err
+			}
+			result.HistoryCreated += count
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func upsertCategory(tx *gorm.DB, c CategoryFixture) (*models.Category, bool, error) {
+	if c.Name == "" {
+		return nil, false, errors.New("categoría sin nombre en el fixture")
+	}
+	var category models.Category
+	err := tx.Where("name = ?", c.Name).First(&category).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		category = models.Category{Name: c.Name, Description: c.Description}
+		return &category, true, tx.Create(&category).Error
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	category.Description = c.Description
+	return &category, false, tx.Save(&category).Error
+}
+
+// upsertProduct crea el producto con todos sus campos o, si ya existe, lo
+// actualiza con UpdateProduct para que el cambio quede en el historial.
+func upsertProduct(tx *gorm.DB, p ProductFixture, categories []models.Category) (*models.Product, bool, error) {
+	if p.Name == "" {
+		return nil, false, errors.New("producto sin nombre en el fixture")
+	}
+	var product models.Product
+	err := tx.Where("name = ?", p.Name).First(&product).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		product = models.Product{
+			Name:        p.Name,
+			Description: p.Description,
+			Price:       p.Price,
+			Stock:       p.Stock,
+		}
+		if err := tx.Omit("Categories").Create(&product).Error; err != nil {
+			return nil, false, err
+		}
+		if err := tx.Model(&product).Association("Categories").Replace(&categories); err != nil {
+			return nil, false, err
+		}
+		product.Categories = categories
+		return &product, true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	categoryIDs := make([]uint, len(categories))
+	for i, c := range categories {
+		categoryIDs[i] = c.ID
+	}
+	reason := "seed"
+	updated, _, err := services.NewProductService(tx).UpdateProduct(tx.Statement.Context, product.ID, &models.UpdateProductRequest{
+		Name:        &p.Name,
+		Description: &p.Description,
+		Price:       &p.Price,
+		Stock:       &p.Stock,
+		Categories:  &categoryIDs,
+		Reason:      &reason,
+	}, "seed")
+	if err != nil {
+		return nil, false, err
+	}
+	return updated, false, nil
+}
+
+// insertHistory registra los cambios del fixture que todavia no existan para
+// el producto, identificandolos por su fecha. Cada entrada es su propio cambio.
+func insertHistory(tx *gorm.DB, product *models.Product, entries []HistoryFixture) (int, error) {
+	created := 0
+	categoryIDs := product.CategoryIDs()
+	for i := 1; i < len(entries); i++ {
+		prev, entry := entries[i-1], entries[i]
+
+		var count int64
+		err := tx.Model(&models.ProductHistory{}).
+			Where("product_id = ? AND changed_at = ?", product.ID, entry.ChangedAt).
+			Count(&count).Error
+		if err != nil {
+			return created, err
+		}
+		if count > 0 {
+			continue
+		}
+
+		history := models.ProductHistory{
+			ProductID:      product.ID,
+			ChangeSetID:    services.NewChangeSetID(),
+			Actor:          entry.Actor,
+			Reason:         entry.Reason,
+			OldName:        product.Name,
+			NewName:        product.Name,
+			OldDescription: product.Description,
+			NewDescription: product.Description,
+			OldPrice:       prev.Price,
+			NewPrice:       entry.Price,
+			OldStock:       prev.Stock,
+			NewStock:       entry.Stock,
+			OldCategoryIDs: categoryIDs,
+			NewCategoryIDs: categoryIDs,
+			ChangedAt:      entry.ChangedAt,
+		}
+		if history.Actor == "" {
+			history.Actor = "seed"
+		}
+		if err := tx.Omit("Product").Create(&history).Error; err != nil {
+			return created, err
+		}
+		created++
+	}
+	return created, nil
+}
+
+var syntheticCategories = []string{"Electrónica", "Computación", "Hogar", "Deportes", "Juguetes", "Librería"}
+
+// Generate arma un fixture con n productos sinteticos para pruebas de carga.
+// El resultado depende solo de n y seed, por lo que se puede volver a aplicar
+// sin crear duplicados.
+func Generate(n int, seed int64) *Fixture {
+	rnd := rand.New(rand.NewSource(seed))
+
+	fixture := &Fixture{}
+	for _, name := range syntheticCategories {
+		fixture.Categories = append(fixture.Categories, CategoryFixture{
+			Name:        name,
+			Description: "Categoría de " + strings.ToLower(name),
+		})
+	}
+
+	for i := 1; i <= n; i++ {
+		categories := []string{syntheticCategories[rnd.Intn(len(syntheticCategories))]}
+		if rnd.Intn(3) == 0 {
+			extra := syntheticCategories[rnd.Intn(len(syntheticCategories))]
+			if extra != categories[0] {
+				categories = append(categories, extra)
+			}
+		}
+		fixture.Products = append(fixture.Products, ProductFixture{
+			Name:        fmt.Sprintf("Producto sintético %06d", i),
+			Description: fmt.Sprintf("Producto generado automáticamente (semilla %d)", seed),
+			Price:       float64(rnd.Intn(500000)) / 100,
+			Stock:       rnd.Intn(1000),
+			Categories:  categories,
+		})
+	}
+	return fixture
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"qisur-challenge/apperrors"
+	"qisur-challenge/config"
+	"qisur-challenge/i18n"
+	"qisur-challenge/models"
+	"qisur-challenge/repository"
+	"qisur-challenge/tracing"
+
+	"github.com/golang-jwt/jwt"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var ErrInvalidCredentials = apperrors.Unauthorized(apperrors.CodeInvalidCredentials, "Credenciales inválidas")
+
+// defaultAdminUsername y defaultAdminPassword solo se aceptan mientras no haya
+// ningun usuario creado, para poder operar una instalacion nueva, y pueden
+// deshabilitarse con AUTH_DEFAULT_ADMIN=false.
+const (
+	defaultAdminUsername = "admin"
+	defaultAdminPassword = "admin"
+)
+
+type AuthService interface {
+	Authenticate(ctx context.Context, username, password string) (*models.User, error)
+	CreateUser(ctx context.Context, username, password, role, language string) (*models.User, error)
+	SetLanguage(ctx context.Context, username, language string) error
+}
+
+type authService struct {
+	userRepo repository.UserRepository
+}
+
+func NewAuthService(db *gorm.DB) AuthService {
+	return &authService{
+		userRepo: repository.NewUserRepository(db),
+	}
+}
+
+// Authenticate valida las credenciales y devuelve el usuario. El
+// administrador por defecto no existe en la base y se devuelve sin ID.
+func (s *authService) Authenticate(ctx context.Context, username, password string) (_ *models.User, err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.Authenticate")
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.userRepo.GetByUsername(ctx, username)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		if !config.AppConfig.Auth.DefaultAdmin {
+			return nil, ErrInvalidCredentials
+		}
+		count, err := s.userRepo.Count(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if count == 0 && username == defaultAdminUsername && password == defaultAdminPassword {
+			return &models.User{Username: defaultAdminUsername, Role: models.RoleAdmin}, nil
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+func (s *authService) CreateUser(ctx context.Context, username, password, role, language string) (_ *models.User, err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.CreateUser")
+	defer func() { tracing.End(span, err) }()
+
+	if username == "" || password == "" {
+		return nil, apperrors.Validation(apperrors.CodeValidation, "el usuario y la contraseña son obligatorios").WithKey(apperrors.KeyCredentialsRequired)
+	}
+	if role == "" {
+		role = models.RoleUser
+	}
+	if role != models.RoleAdmin && role != models.RoleUser {
+		return nil, apperrors.Validation(apperrors.CodeValidation, "rol '%s' inválido", role).
+			With("role", role).WithKey(apperrors.KeyUnsupportedRole)
+	}
+	language, err = normalizeLanguage(language)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	user := &models.User{Username: username, PasswordHash: string(hash), Role: role, Language: language}
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// SetLanguage cambia el idioma preferido del usuario; "" lo borra y vuelve a
+// usarse Accept-Language. Se aplica a los tokens generados despues del cambio.
+func (s *authService) SetLanguage(ctx context.Context, username, language string) (err error) {
+	ctx, span := tracing.Start(ctx, "AuthService.SetLanguage")
+	defer func() { tracing.End(span, err) }()
+
+	language, err = normalizeLanguage(language)
+	if err != nil {
+		return err
+	}
+	user, err := s.userRepo.GetByUsername(ctx, username)
+	if err != nil {
+		return err
+	}
+	user.Language = language
+	return s.userRepo.Update(ctx, user)
+}
+
+// normalizeLanguage acepta "" (sin preferencia) o un idioma soportado, con o
+// sin region ("en-US" se guarda como "en").
+func normalizeLanguage(language string) (string, error) {
+	if language == "" {
+		return "", nil
+	}
+	lang, ok := i18n.Parse(language)
+	if !ok {
+		return "", apperrors.Validation(apperrors.CodeValidation, "idioma '%s' no soportado", language).
+			With("language", language).WithKey(apperrors.KeyUnsupportedLanguage)
+	}
+	return string(lang), nil
+}
+
+// GenerateToken firma un JWT para el usuario y su rol con la clave configurada
+// en JWT_SECRET. Si language no es "", el token lleva el idioma preferido del
+// usuario y AuthMiddleware lo usa en lugar de Accept-Language.
+func GenerateToken(username, role, language string, ttl time.Duration) (string, error) {
+	claims := jwt.MapClaims{
+		"username": username,
+		"role":     role,
+		"exp":      time.Now().Add(ttl).Unix(),
+	}
+	if language != "" {
+		claims["lang"] = language
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(config.AppConfig.Auth.JWTSecret))
+}
@@ -1,20 +1,22 @@
 package services
 
 import (
+	"context"
 	"qisur-challenge/models"
 	"qisur-challenge/repository"
+	"qisur-challenge/tracing"
 
 	"gorm.io/gorm"
 )
 
 type CategoryService interface {
-	GetAllCategories() ([]models.Category, error)
-	GetCategoryByID(id uint) (*models.Category, error)
+	GetAllCategories(ctx context.Context) ([]models.Category, error)
+	GetCategoryByID(ctx context.Context, id uint) (*models.Category, error)
 	ConvertToCategoryDTO(category *models.Category) models.CategoryWithProductsDTO
 	ConvertToCategoryWithProductsDTOs(categories []models.Category) []models.CategoryWithProductsDTO
-	CreateCategory(category *models.Category) error
-	UpdateCategory(category *models.Category) error
-	DeleteCategory(category *models.Category) error
+	CreateCategory(ctx context.Context, category *models.Category) error
+	UpdateCategory(ctx context.Context, category *models.Category) error
+	DeleteCategory(ctx context.Context, category *models.Category) error
 }
 
 type categoryService struct {
@@ -29,12 +31,18 @@ func NewCategoryService(db *gorm.DB) CategoryService {
 	}
 }
 
-func (s *categoryService) GetAllCategories() ([]models.Category, error) {
-	return s.categoryRepo.GetAll()
+func (s *categoryService) GetAllCategories(ctx context.Context) (_ []models.Category, err error) {
+	ctx, span := tracing.Start(ctx, "CategoryService.GetAllCategories")
+	defer func() { tracing.End(span, err) }()
+
+	return s.categoryRepo.GetAll(ctx)
 }
 
-func (s *categoryService) GetCategoryByID(id uint) (*models.Category, error) {
-	return s.categoryRepo.GetByID(id)
+func (s *categoryService) GetCategoryByID(ctx context.Context, id uint) (_ *models.Category, err error) {
+	ctx, span := tracing.Start(ctx, "CategoryService.GetCategoryByID")
+	defer func() { tracing.End(span, err) }()
+
+	return s.categoryRepo.GetByID(ctx, id)
 }
 
 func (s *categoryService) ConvertToCategoryDTO(category *models.Category) models.CategoryWithProductsDTO {
@@ -61,14 +69,23 @@ func (s *categoryService) ConvertToCategoryWithProductsDTOs(categories []models.
 	return dtos
 }
 
-func (s *categoryService) CreateCategory(category *models.Category) error {
-	return s.categoryRepo.Create(category)
+func (s *categoryService) CreateCategory(ctx context.Context, category *models.Category) (err error) {
+	ctx, span := tracing.Start(ctx, "CategoryService.CreateCategory")
+	defer func() { tracing.End(span, err) }()
+
+	return s.categoryRepo.Create(ctx, category)
 }
 
-func (s *categoryService) UpdateCategory(category *models.Category) error {
-	return s.categoryRepo.Update(category)
+func (s *categoryService) UpdateCategory(ctx context.Context, category *models.Category) (err error) {
+	ctx, span := tracing.Start(ctx, "CategoryService.UpdateCategory")
+	defer func() { tracing.End(span, err) }()
+
+	return s.categoryRepo.Update(ctx, category)
 }
 
-func (s *categoryService) DeleteCategory(category *models.Category) error {
-	return s.categoryRepo.Delete(category)
+func (s *categoryService) DeleteCategory(ctx context.Context, category *models.Category) (err error) {
+	ctx, span := tracing.Start(ctx, "CategoryService.DeleteCategory")
+	defer func() { tracing.End(span, err) }()
+
+	return s.categoryRepo.Delete(ctx, category)
 }
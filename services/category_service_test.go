@@ -0,0 +1,131 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"qisur-challenge/apperrors"
+	"qisur-challenge/models"
+	"qisur-challenge/repository/memory"
+	"qisur-challenge/services"
+)
+
+func TestCategoryServiceCreateAndUpdate(t *testing.T) {
+	tests := []struct {
+		name     string
+		run      func(ctx context.Context, s services.CategoryService, gaming models.Category) error
+		wantCode string
+	}{
+		{
+			name: "crear con nombre nuevo",
+			run: func(ctx context.Context, s services.CategoryService, _ models.Category) error {
+				return s.CreateCategory(ctx, &models.Category{Name: "Audio"})
+			},
+		},
+		{
+			name: "crear con nombre repetido",
+			run: func(ctx context.Context, s services.CategoryService, _ models.Category) error {
+				return s.CreateCategory(ctx, &models.Category{Name: "Oficina"})
+			},
+			wantCode: apperrors.CodeCategoryNameTaken,
+		},
+		{
+			name: "renombrar con nombre repetido",
+			run: func(ctx context.Context, s services.CategoryService, gaming models.Category) error {
+				return s.UpdateCategory(ctx, &models.Category{ID: gaming.ID, Name: "Oficina"})
+			},
+			wantCode: apperrors.CodeCategoryNameTaken,
+		},
+		{
+			name: "actualizar sin cambiar el nombre",
+			run: func(ctx context.Context, s services.CategoryService, gaming models.Category) error {
+				return s.UpdateCategory(ctx, &models.Category{ID: gaming.ID, Name: "Gaming", Description: "otra"})
+			},
+		},
+		{
+			name: "actualizar una categoria inexistente",
+			run: func(ctx context.Context, s services.CategoryService, _ models.Category) error {
+				return s.UpdateCategory(ctx, &models.Category{ID: 9999, Name: "Nueva"})
+			},
+			wantCode: apperrors.CodeCategoryNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := services.NewCategoryServiceWithRepository(memory.NewCategoryRepository(memory.NewStore()))
+			gaming := models.Category{Name: "Gaming"}
+			for _, c := range []*models.Category{&gaming, {Name: "Oficina"}} {
+				if err := s.CreateCategory(ctx, c); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := tt.run(ctx, s, gaming)
+			if tt.wantCode != "" {
+				assertCode(t, err, tt.wantCode)
+			} else if err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+		})
+	}
+}
+
+func TestCategoryServiceUpdateKeepsProducts(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	s := services.NewCategoryServiceWithRepository(memory.NewCategoryRepository(store))
+	gaming := models.Category{Name: "Gaming"}
+	if err := s.CreateCategory(ctx, &gaming); err != nil {
+		t.Fatal(err)
+	}
+	mouse := models.Product{Name: "Mouse", Categories: []models.Category{gaming}}
+	if err := memory.NewProductRepository(store).Create(ctx, &mouse); err != nil {
+		t.Fatal(err)
+	}
+
+	update := models.Category{ID: gaming.ID, Name: "Juegos", Description: "consolas y pc"}
+	if err := s.UpdateCategory(ctx, &update); err != nil {
+		t.Fatal(err)
+	}
+	if !update.CreatedAt.Equal(gaming.CreatedAt) || len(update.Products) != 1 {
+		t.Errorf("la categoria actualizada debe conservar fecha de creacion y productos: %+v", update)
+	}
+
+	stored, err := s.GetCategoryByID(ctx, gaming.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dto := s.ConvertToCategoryDTO(stored)
+	if dto.Name != "Juegos" || dto.Description != "consolas y pc" || len(dto.Products) != 1 || dto.Products[0].Name != "Mouse" {
+		t.Errorf("categoria guardada incorrecta: %+v", dto)
+	}
+}
+
+func TestCategoryServiceDeleteUnlinksProducts(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	s := services.NewCategoryServiceWithRepository(memory.NewCategoryRepository(store))
+	products := memory.NewProductRepository(store)
+	gaming := models.Category{Name: "Gaming"}
+	if err := s.CreateCategory(ctx, &gaming); err != nil {
+		t.Fatal(err)
+	}
+	mouse := models.Product{Name: "Mouse", Categories: []models.Category{gaming}}
+	if err := products.Create(ctx, &mouse); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.DeleteCategory(ctx, &gaming); err != nil {
+		t.Fatal(err)
+	}
+	_, err := s.GetCategoryByID(ctx, gaming.ID)
+	assertCode(t, err, apperrors.CodeCategoryNotFound)
+	stored, err := products.GetByID(ctx, mouse.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored.Categories) != 0 {
+		t.Errorf("el producto sigue asociado a la categoria eliminada: %+v", stored.Categories)
+	}
+}
@@ -0,0 +1,19 @@
+package services
+
+import "qisur-challenge/repository"
+
+// NewProductServiceWithRepositories crea el servicio sobre los repositorios
+// indicados, por ejemplo los de repository/memory. Sin base de datos
+// SearchCategories no esta disponible.
+func NewProductServiceWithRepositories(productRepo repository.ProductRepository, priceChangeRepo repository.PriceChangeRepository) *productService {
+	return &productService{
+		productRepo:     productRepo,
+		priceChangeRepo: priceChangeRepo,
+	}
+}
+
+// NewCategoryServiceWithRepository crea el servicio sobre el repositorio
+// indicado, por ejemplo el de repository/memory.
+func NewCategoryServiceWithRepository(categoryRepo repository.CategoryRepository) CategoryService {
+	return &categoryService{categoryRepo: categoryRepo}
+}
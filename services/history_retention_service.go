@@ -0,0 +1,217 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"qisur-challenge/logger"
+	"qisur-challenge/models"
+	"qisur-challenge/repository"
+	"qisur-challenge/tracing"
+
+	"gorm.io/gorm"
+)
+
+// RetentionPolicy define cuanto historial se conserva. Las entradas mas nuevas
+// que KeepDays se guardan completas; entre KeepDays y DeleteMonths se reducen a
+// una por producto y dia; las anteriores a DeleteMonths se eliminan. Un valor
+// 0 desactiva la etapa correspondiente. Con ambas etapas activas KeepDays
+// tiene que ser menor que DeleteMonths (lo valida la configuracion).
+type RetentionPolicy struct {
+	KeepDays     int `json:"keep_days"`
+	DeleteMonths int `json:"delete_months"`
+}
+
+type PruneReport struct {
+	DryRun          bool            `json:"dry_run"`
+	Policy          RetentionPolicy `json:"policy"`
+	RanAt           time.Time       `json:"ran_at"`
+	DownsampleFrom  *time.Time      `json:"downsample_from"`
+	DeleteBefore    *time.Time      `json:"delete_before"`
+	Deleted         int64           `json:"deleted"`
+	Downsampled     int64           `json:"downsampled"`
+	CompactedGroups int64           `json:"compacted_groups"`
+	Error           string          `json:"error,omitempty"`
+}
+
+type HistoryRetentionService interface {
+	Policy() RetentionPolicy
+	Prune(ctx context.Context, dryRun bool) (*PruneReport, error)
+	Preview(ctx context.Context) (*PruneReport, error)
+	Run(ctx context.Context, dryRun bool) (*PruneReport, error)
+	LastReport() *PruneReport
+	Enabled() bool
+}
+
+type historyRetentionService struct {
+	historyRepo repository.HistoryRepository
+	policy      RetentionPolicy
+
+	mu   sync.Mutex
+	last *PruneReport
+}
+
+func NewHistoryRetentionService(db *gorm.DB, policy RetentionPolicy) HistoryRetentionService {
+	return &historyRetentionService{
+		historyRepo: repository.NewHistoryRepository(db),
+		policy:      policy,
+	}
+}
+
+func (s *historyRetentionService) Policy() RetentionPolicy {
+	return s.policy
+}
+
+func (s *historyRetentionService) LastReport() *PruneReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.last
+}
+
+// Prune aplica la politica de retencion. En modo dryRun solo calcula cuantas
+// entradas se eliminarian.
+func (s *historyRetentionService) Prune(ctx context.Context, dryRun bool) (_ *PruneReport, err error) {
+	ctx, span := tracing.Start(ctx, "HistoryRetentionService.Prune")
+	defer func() { tracing.End(span, err) }()
+
+	now := time.Now().UTC()
+	report := &PruneReport{DryRun: dryRun, Policy: s.policy, RanAt: now}
+
+	var deleteBefore time.Time
+	if s.policy.DeleteMonths > 0 {
+		deleteBefore = now.AddDate(0, -s.policy.DeleteMonths, 0)
+		report.DeleteBefore = &deleteBefore
+	}
+
+	err = s.historyRepo.Transaction(ctx, func(repo repository.HistoryRepository) error {
+		// La transaccion puede repetirse, asi que los contadores empiezan de cero.
+		report.Deleted, report.Downsampled, report.CompactedGroups = 0, 0, 0
+		if s.policy.DeleteMonths > 0 {
+			if dryRun {
+				count, err := repo.CountBefore(ctx, deleteBefore)
+				if err != nil {
+					return err
+				}
+				report.Deleted = count
+			} else {
+				count, err := repo.DeleteBefore(ctx, deleteBefore)
+				if err != nil {
+					return err
+				}
+				report.Deleted = count
+			}
+		}
+
+		if s.policy.KeepDays > 0 {
+			downsampleFrom := now.AddDate(0, 0, -s.policy.KeepDays)
+			report.DownsampleFrom = &downsampleFrom
+			return s.downsample(ctx, repo, deleteBefore, downsampleFrom, dryRun, report)
+		}
+		return nil
+	})
+	if err != nil {
+		report.Error = err.Error()
+	}
+	return report, err
+}
+
+// Preview informa lo mismo que Prune en modo dryRun pero solo con consultas
+// COUNT, sin transaccion ni recorrer el historial, para poder consultarlo a
+// menudo.
+func (s *historyRetentionService) Preview(ctx context.Context) (_ *PruneReport, err error) {
+	ctx, span := tracing.Start(ctx, "HistoryRetentionService.Preview")
+	defer func() { tracing.End(span, err) }()
+
+	now := time.Now().UTC()
+	report := &PruneReport{DryRun: true, Policy: s.policy, RanAt: now}
+
+	var deleteBefore time.Time
+	if s.policy.DeleteMonths > 0 {
+		deleteBefore = now.AddDate(0, -s.policy.DeleteMonths, 0)
+		report.DeleteBefore = &deleteBefore
+		if report.Deleted, err = s.historyRepo.CountBefore(ctx, deleteBefore); err != nil {
+			return nil, err
+		}
+	}
+	if s.policy.KeepDays > 0 {
+		downsampleFrom := now.AddDate(0, 0, -s.policy.KeepDays)
+		report.DownsampleFrom = &downsampleFrom
+		if report.CompactedGroups, report.Downsampled, err = s.historyRepo.CountDownsample(ctx, deleteBefore, downsampleFrom); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// downsample reduce las entradas de cada producto y dia a una sola, conservando
+// la ultima con los valores anteriores de la primera para que el historial siga
+// siendo continuo.
+func (s *historyRetentionService) downsample(ctx context.Context, repo repository.HistoryRepository, start, end time.Time, dryRun bool, report *PruneReport) error {
+	type group struct {
+		first *models.ProductHistory
+		last  *models.ProductHistory
+		drop  []uint
+	}
+	var groups []*group
+	var current *group
+
+	err := repo.EachBetween(ctx, start, end, func(h *models.ProductHistory) error {
+		day := truncateToInterval(h.ChangedAt, "day")
+		if current != nil && current.last.ProductID == h.ProductID && truncateToInterval(current.last.ChangedAt, "day").Equal(day) {
+			current.drop = append(current.drop, current.last.ID)
+			current.last = h
+			return nil
+		}
+		if current != nil && len(current.drop) > 0 {
+			groups = append(groups, current)
+		}
+		current = &group{first: h, last: h}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if current != nil && len(current.drop) > 0 {
+		groups = append(groups, current)
+	}
+
+	for _, g := range groups {
+		report.CompactedGroups++
+		report.Downsampled += int64(len(g.drop))
+		if dryRun {
+			continue
+		}
+
+		g.last.OldName = g.first.OldName
+		g.last.OldDescription = g.first.OldDescription
+		g.last.OldPrice = g.first.OldPrice
+		g.last.OldStock = g.first.OldStock
+		g.last.OldCategoryIDs = g.first.OldCategoryIDs
+		if err := repo.Update(ctx, g.last); err != nil {
+			return err
+		}
+		if err := repo.DeleteByIDs(ctx, g.drop); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run aplica la politica y guarda el reporte como ultima ejecucion. Es la
+// funcion que ejecuta el scheduler.
+func (s *historyRetentionService) Run(ctx context.Context, dryRun bool) (*PruneReport, error) {
+	report, err := s.Prune(ctx, dryRun)
+	s.mu.Lock()
+	s.last = report
+	s.mu.Unlock()
+	if err == nil {
+		logger.FromContext(ctx).Info("Depuración de historial", "dry_run", dryRun, "deleted", report.Deleted, "downsampled", report.Downsampled)
+	}
+	return report, err
+}
+
+// Enabled indica si alguna etapa de la politica esta activa.
+func (s *historyRetentionService) Enabled() bool {
+	return s.policy.KeepDays > 0 || s.policy.DeleteMonths > 0
+}
@@ -0,0 +1,310 @@
+package services
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"qisur-challenge/apperrors"
+	"qisur-challenge/tracing"
+	"strconv"
+	"strings"
+	"time"
+
+	"qisur-challenge/models"
+	"qisur-challenge/repository"
+)
+
+// exportBatchSize es la cantidad de productos que se leen de la base por lote
+// durante una exportacion.
+const exportBatchSize = 500
+
+// ExportColumns son las columnas disponibles para exportar, en el orden por defecto.
+var ExportColumns = []string{"id", "name", "description", "price", "stock", "categories", "created_at", "updated_at"}
+
+var exportContentTypes = map[string]string{
+	"csv":    "text/csv; charset=utf-8",
+	"ndjson": "application/x-ndjson",
+	"xlsx":   "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+type ExportOptions struct {
+	Format  string
+	Columns []string
+	Name    string
+	Sort    string
+}
+
+// ExportContentType devuelve el Content-Type del formato, o "" si no es valido.
+func ExportContentType(format string) string {
+	return exportContentTypes[format]
+}
+
+// ValidateExportColumns verifica que todas las columnas existan.
+func ValidateExportColumns(columns []string) error {
+	for _, c := range columns {
+		valid := false
+		for _, known := range ExportColumns {
+			if c == known {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return apperrors.Validation(apperrors.CodeInvalidParameter, "columna '%s' inválida", c).With("parameter", "columns").With("value", c).WithKey(apperrors.KeyParameterValue)
+		}
+	}
+	return nil
+}
+
+// ExportProducts escribe en w los productos que cumplen los filtros de la
+// busqueda, leyendolos de la base por lotes a medida que se escriben.
+func (ps *productService) ExportProducts(ctx context.Context, w io.Writer, opts ExportOptions) (err error) {
+	ctx, span := tracing.Start(ctx, "ProductService.ExportProducts")
+	defer func() { tracing.End(span, err) }()
+
+	columns := opts.Columns
+	if len(columns) == 0 {
+		columns = ExportColumns
+	}
+	if err := ValidateExportColumns(columns); err != nil {
+		return err
+	}
+
+	var writer exportWriter
+	switch opts.Format {
+	case "csv":
+		writer = newCSVExportWriter(w)
+	case "ndjson":
+		writer = newNDJSONExportWriter(w)
+	case "xlsx":
+		writer = newXLSXExportWriter(w)
+	default:
+		return apperrors.Validation(apperrors.CodeInvalidParameter, "formato de exportación '%s' no soportado", opts.Format).With("parameter", "format").With("value", opts.Format).WithKey(apperrors.KeyParameterValue)
+	}
+
+	if err := writer.WriteHeader(columns); err != nil {
+		return err
+	}
+	filter := repository.ProductFilter{Name: opts.Name, Sort: opts.Sort}
+	err = ps.productRepo.Stream(ctx, filter, exportBatchSize, func(batch []models.Product) error {
+		for i := range batch {
+			if err := writer.WriteRow(exportValues(&batch[i], columns)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+func exportValues(p *models.Product, columns []string) []interface{} {
+	values := make([]interface{}, len(columns))
+	for i, c := range columns {
+		switch c {
+		case "id":
+			values[i] = p.ID
+		case "name":
+			values[i] = p.Name
+		case "description":
+			values[i] = p.Description
+		case "price":
+			values[i] = p.Price
+		case "stock":
+			values[i] = p.Stock
+		case "categories":
+			names := make([]string, len(p.Categories))
+			for j, category := range p.Categories {
+				names[j] = category.Name
+			}
+			values[i] = names
+		case "created_at":
+			values[i] = p.CreatedAt
+		case "updated_at":
+			values[i] = p.UpdatedAt
+		}
+	}
+	return values
+}
+
+// exportCell convierte un valor a texto para los formatos tabulares.
+func exportCell(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case uint:
+		return strconv.FormatUint(uint64(v), 10)
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []string:
+		return strings.Join(v, "|")
+	case time.Time:
+		return v.Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+type exportWriter interface {
+	WriteHeader(columns []string) error
+	WriteRow(values []interface{}) error
+	Close() error
+}
+
+type csvExportWriter struct {
+	w *csv.Writer
+}
+
+func newCSVExportWriter(w io.Writer) *csvExportWriter {
+	return &csvExportWriter{w: csv.NewWriter(w)}
+}
+
+func (e *csvExportWriter) WriteHeader(columns []string) error {
+	return e.w.Write(columns)
+}
+
+func (e *csvExportWriter) WriteRow(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, v := range values {
+		record[i] = exportCell(v)
+	}
+	return e.w.Write(record)
+}
+
+func (e *csvExportWriter) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type ndjsonExportWriter struct {
+	w       *bufio.Writer
+	columns []string
+}
+
+func newNDJSONExportWriter(w io.Writer) *ndjsonExportWriter {
+	return &ndjsonExportWriter{w: bufio.NewWriter(w)}
+}
+
+func (e *ndjsonExportWriter) WriteHeader(columns []string) error {
+	e.columns = columns
+	return nil
+}
+
+func (e *ndjsonExportWriter) WriteRow(values []interface{}) error {
+	record := make(map[string]interface{}, len(values))
+	for i, v := range values {
+		record[e.columns[i]] = v
+	}
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := e.w.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (e *ndjsonExportWriter) Close() error {
+	return e.w.Flush()
+}
+
+// xlsxExportWriter genera un libro XLSX minimo con una sola hoja, escribiendo
+// las filas directamente en el zip a medida que llegan.
+type xlsxExportWriter struct {
+	zip   *zip.Writer
+	sheet *bufio.Writer
+	row   int
+}
+
+func newXLSXExportWriter(w io.Writer) *xlsxExportWriter {
+	return &xlsxExportWriter{zip: zip.NewWriter(w)}
+}
+
+var xlsxStaticFiles = []struct {
+	name    string
+	content string
+}{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Productos" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`},
+}
+
+func (e *xlsxExportWriter) WriteHeader(columns []string) error {
+	for _, f := range xlsxStaticFiles {
+		fw, err := e.zip.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, f.content); err != nil {
+			return err
+		}
+	}
+
+	fw, err := e.zip.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	e.sheet = bufio.NewWriter(fw)
+	if _, err := e.sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`); err != nil {
+		return err
+	}
+
+	header := make([]interface{}, len(columns))
+	for i, c := range columns {
+		header[i] = c
+	}
+	return e.WriteRow(header)
+}
+
+func (e *xlsxExportWriter) WriteRow(values []interface{}) error {
+	e.row++
+	if _, err := fmt.Fprintf(e.sheet, `<row r="%d">`, e.row); err != nil {
+		return err
+	}
+	for _, v := range values {
+		switch v.(type) {
+		case uint, int, float64:
+			if _, err := fmt.Fprintf(e.sheet, `<c t="n"><v>%s</v></c>`, exportCell(v)); err != nil {
+				return err
+			}
+		default:
+			if _, err := e.sheet.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`); err != nil {
+				return err
+			}
+			if err := xml.EscapeText(e.sheet, []byte(exportCell(v))); err != nil {
+				return err
+			}
+			if _, err := e.sheet.WriteString(`</t></is></c>`); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := e.sheet.WriteString(`</row>`)
+	return err
+}
+
+func (e *xlsxExportWriter) Close() error {
+	if _, err := e.sheet.WriteString(`</sheetData></worksheet>`); err != nil {
+		return err
+	}
+	if err := e.sheet.Flush(); err != nil {
+		return err
+	}
+	return e.zip.Close()
+}
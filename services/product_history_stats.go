@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"qisur-challenge/apperrors"
+	"qisur-challenge/tracing"
+	"time"
+
+	"qisur-challenge/models"
+)
+
+// maxHistoryBuckets limita la cantidad de intervalos que puede devolver una
+// consulta de estadisticas.
+const maxHistoryBuckets = 5000
+
+var historyIntervals = map[string]bool{
+	"hour":  true,
+	"day":   true,
+	"week":  true,
+	"month": true,
+}
+
+// IsValidHistoryInterval indica si el intervalo es uno de hour, day, week o month.
+func IsValidHistoryInterval(interval string) bool {
+	return historyIntervals[interval]
+}
+
+func truncateToInterval(t time.Time, interval string) time.Time {
+	t = t.UTC()
+	switch interval {
+	case "hour":
+		return t.Truncate(time.Hour)
+	case "week":
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	case "month":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+}
+
+func nextInterval(t time.Time, interval string) time.Time {
+	switch interval {
+	case "hour":
+		return t.Add(time.Hour)
+	case "week":
+		return t.AddDate(0, 0, 7)
+	case "month":
+		return t.AddDate(0, 1, 0)
+	default:
+		return t.AddDate(0, 0, 1)
+	}
+}
+
+// earliestStart devuelve el inicio del primer intervalo de un rango que
+// termina en end y tiene maxHistoryBuckets intervalos.
+func earliestStart(end time.Time, interval string) time.Time {
+	n := maxHistoryBuckets - 1
+	last := truncateToInterval(end.Add(-time.Nanosecond), interval)
+	switch interval {
+	case "hour":
+		return last.Add(-time.Duration(n) * time.Hour)
+	case "week":
+		return last.AddDate(0, 0, -7*n)
+	case "month":
+		return last.AddDate(0, -n, 0)
+	default:
+		return last.AddDate(0, 0, -n)
+	}
+}
+
+// GetProductHistoryStats agrupa el historial de precio y stock del producto en
+// intervalos con valores de apertura, cierre, minimo y maximo. El rango es
+// [start, end); sin start empieza en la creacion del producto, limitado a los
+// ultimos maxHistoryBuckets intervalos. Con fill se incluyen tambien los intervalos sin cambios,
+// repitiendo el ultimo valor.
+func (ps *productService) GetProductHistoryStats(ctx context.Context, id uint, interval string, start, end *time.Time, fill bool) (_ *models.HistoryStatsDTO, err error) {
+	ctx, span := tracing.Start(ctx, "ProductService.GetProductHistoryStats")
+	defer func() { tracing.End(span, err) }()
+
+	if !IsValidHistoryInterval(interval) {
+		return nil, apperrors.Validation(apperrors.CodeInvalidParameter, "intervalo '%s' inválido", interval).With("parameter", "interval").With("value", interval).WithKey(apperrors.KeyParameterValue)
+	}
+
+	product, err := ps.productRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rangeEnd := time.Now().UTC()
+	if end != nil {
+		rangeEnd = end.UTC()
+	}
+	rangeStart := rangeEnd
+	if start != nil {
+		rangeStart = start.UTC()
+	} else if product.CreatedAt.Year() > 1 {
+		rangeStart = product.CreatedAt.UTC()
+	} else if oldest, err := ps.productRepo.FirstHistoryAfter(ctx, id, time.Time{}); err != nil {
+		return nil, err
+	} else if oldest != nil {
+		rangeStart = oldest.ChangedAt.UTC()
+	}
+	if limit := earliestStart(rangeEnd, interval); start == nil && rangeStart.Before(limit) {
+		// Sin start se devuelven los ultimos intervalos en lugar de rechazar
+		// la consulta de un producto antiguo.
+		rangeStart = limit
+	}
+
+	history, err := ps.productRepo.GetHistory(ctx, id, &rangeStart, &rangeEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	// Valores vigentes al inicio del rango.
+	price, stock := product.Price, product.Stock
+	if len(history) > 0 {
+		price, stock = history[0].OldPrice, history[0].OldStock
+	} else if before, err := ps.productRepo.LastHistoryBefore(ctx, id, rangeStart); err != nil {
+		return nil, err
+	} else if before != nil {
+		price, stock = before.NewPrice, before.NewStock
+	} else if after, err := ps.productRepo.FirstHistoryAfter(ctx, id, rangeStart); err != nil {
+		return nil, err
+	} else if after != nil {
+		price, stock = after.OldPrice, after.OldStock
+	}
+
+	// rangeEnd es exclusivo: el ultimo intervalo es el que contiene el instante
+	// anterior.
+	first := truncateToInterval(rangeStart, interval)
+	last := truncateToInterval(rangeEnd.Add(-time.Nanosecond), interval)
+	count := 0
+	for b := first; !b.After(last); b = nextInterval(b, interval) {
+		count++
+		if count > maxHistoryBuckets {
+			return nil, apperrors.Validation(apperrors.CodeValidation, "el rango solicitado supera los %d intervalos", maxHistoryBuckets).With("max_intervals", maxHistoryBuckets).WithKey(apperrors.KeyMaxIntervals)
+		}
+	}
+
+	stats := &models.HistoryStatsDTO{
+		ProductID: id,
+		Interval:  interval,
+		Start:     rangeStart,
+		End:       rangeEnd,
+		Buckets:   []models.HistoryBucketDTO{},
+	}
+	openPrice, openStock := price, stock
+
+	i := 0
+	for b := first; !b.After(last); b = nextInterval(b, interval) {
+		next := nextInterval(b, interval)
+		bucket := models.HistoryBucketDTO{
+			Start:      b,
+			OpenPrice:  price,
+			ClosePrice: price,
+			MinPrice:   price,
+			MaxPrice:   price,
+			OpenStock:  stock,
+			CloseStock: stock,
+			MinStock:   stock,
+			MaxStock:   stock,
+		}
+		for ; i < len(history) && history[i].ChangedAt.Before(next); i++ {
+			h := history[i]
+			price, stock = h.NewPrice, h.NewStock
+			bucket.ClosePrice, bucket.CloseStock = price, stock
+			bucket.MinPrice = min(bucket.MinPrice, price)
+			bucket.MaxPrice = max(bucket.MaxPrice, price)
+			bucket.MinStock = min(bucket.MinStock, stock)
+			bucket.MaxStock = max(bucket.MaxStock, stock)
+			bucket.Changes++
+		}
+		if bucket.Changes == 0 {
+			if !fill {
+				continue
+			}
+			bucket.Filled = true
+		}
+		stats.Buckets = append(stats.Buckets, bucket)
+	}
+
+	stats.PriceChangePct = percentChange(openPrice, price)
+	stats.StockChangePct = percentChange(float64(openStock), float64(stock))
+	return stats, nil
+}
+
+func percentChange(from, to float64) *float64 {
+	if from == 0 {
+		return nil
+	}
+	pct := (to - from) / from * 100
+	return &pct
+}
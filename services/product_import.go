@@ -0,0 +1,447 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"qisur-challenge/apperrors"
+	"qisur-challenge/tracing"
+	"strconv"
+	"strings"
+
+	"qisur-challenge/i18n"
+	"qisur-challenge/models"
+	"qisur-challenge/repository"
+
+	"gorm.io/gorm"
+)
+
+const (
+	ImportStatusCreated = "created"
+	ImportStatusUpdated = "updated"
+	ImportStatusSkipped = "unchanged"
+	ImportStatusFailed  = "failed"
+)
+
+// importColumnAliases asocia los nombres de columna aceptados con el campo del
+// producto correspondiente.
+var importColumnAliases = map[string]string{
+	"name":        "name",
+	"nombre":      "name",
+	"description": "description",
+	"descripcion": "description",
+	"descripción": "description",
+	"price":       "price",
+	"precio":      "price",
+	"stock":       "stock",
+	"categories":  "categories",
+	"categorias":  "categories",
+	"categorías":  "categories",
+}
+
+type ImportOptions struct {
+	Format string
+	DryRun bool
+	Actor  string
+	// Mapping asocia columnas del CSV con campos del producto y tiene
+	// prioridad sobre los nombres reconocidos automaticamente.
+	Mapping map[string]string
+	// Progress, si no es nil, se llama despues de procesar cada fila.
+	Progress func(done, total int)
+}
+
+type ImportRowResult struct {
+	Row       int      `json:"row"`
+	Name      string   `json:"name"`
+	Status    string   `json:"status"`
+	ProductID uint     `json:"product_id,omitempty"`
+	Errors    []string `json:"errors,omitempty"`
+}
+
+type ImportReport struct {
+	DryRun      bool              `json:"dry_run"`
+	ChangeSetID string            `json:"change_set_id"`
+	Total       int               `json:"total"`
+	Created     int               `json:"created"`
+	Updated     int               `json:"updated"`
+	Unchanged   int               `json:"unchanged"`
+	Failed      int               `json:"failed"`
+	Rows        []ImportRowResult `json:"rows"`
+}
+
+type importRow struct {
+	line        int
+	name        string
+	description *string
+	price       *float64
+	stock       *int
+	categories  *[]string
+	errors      []rowError
+}
+
+// rowError es un error de validacion de una fila. El texto se arma con el
+// catalogo de i18n en el idioma de la request al generar el reporte.
+type rowError struct {
+	key    string
+	params map[string]any
+}
+
+func (row *importRow) fail(key string, params map[string]any) {
+	row.errors = append(row.errors, rowError{key: key, params: params})
+}
+
+func (row *importRow) errorTexts(lang i18n.Lang) []string {
+	texts := make([]string, len(row.errors))
+	for i, e := range row.errors {
+		texts[i] = i18n.Text(lang, e.key, e.params)
+	}
+	return texts
+}
+
+// IsValidImportFormat indica si el formato es csv o ndjson.
+func IsValidImportFormat(format string) bool {
+	return format == "csv" || format == "ndjson"
+}
+
+// ImportProducts crea o actualiza productos, identificados por nombre, a partir
+// de un CSV o NDJSON. Todas las filas validas se aplican en una transaccion. Si
+// DryRun es verdadero solo se valida y se compara con los productos existentes,
+// sin escribir nada. Las filas invalidas se informan en el reporte sin
+// interrumpir la importacion.
+func (ps *productService) ImportProducts(ctx context.Context, r io.Reader, opts ImportOptions) (_ *ImportReport, err error) {
+	ctx, span := tracing.Start(ctx, "ProductService.ImportProducts")
+	defer func() { tracing.End(span, err) }()
+
+	var rows []importRow
+	switch opts.Format {
+	case "csv":
+		rows, err = parseImportCSV(r, opts.Mapping)
+	case "ndjson":
+		rows, err = parseImportNDJSON(r)
+	default:
+		return nil, apperrors.Validation(apperrors.CodeInvalidParameter, "formato de importación '%s' no soportado", opts.Format).With("parameter", "format").With("value", opts.Format).WithKey(apperrors.KeyParameterValue)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ImportReport{
+		DryRun:      opts.DryRun,
+		ChangeSetID: NewChangeSetID(),
+		Total:       len(rows),
+		Rows:        make([]ImportRowResult, 0, len(rows)),
+	}
+	lang := i18n.FromContext(ctx)
+
+	importRows := func(repo repository.ProductRepository) error {
+		// La transaccion puede repetirse, asi que el reporte se arma desde cero.
+		report.Created, report.Updated, report.Unchanged, report.Failed = 0, 0, 0, 0
+		report.Rows = report.Rows[:0]
+
+		categoryIDs, err := resolveImportCategories(ctx, repo, rows)
+		if err != nil {
+			return err
+		}
+
+		seen := map[string]int{}
+		for i := range rows {
+			row := rows[i]
+			validateImportRow(&row, categoryIDs, seen)
+
+			result := ImportRowResult{Row: row.line, Name: row.name}
+			if len(row.errors) > 0 {
+				result.Status = ImportStatusFailed
+				result.Errors = row.errorTexts(lang)
+				report.Failed++
+			} else {
+				req := row.toUpdateRequest(categoryIDs)
+				result.Status, result.ProductID, err = importProduct(ctx, repo, req, opts, report.ChangeSetID)
+				if err != nil {
+					return err
+				}
+				switch result.Status {
+				case ImportStatusCreated:
+					report.Created++
+				case ImportStatusUpdated:
+					report.Updated++
+				default:
+					report.Unchanged++
+				}
+			}
+			report.Rows = append(report.Rows, result)
+			if opts.Progress != nil {
+				opts.Progress(i+1, len(rows))
+			}
+		}
+		return nil
+	}
+
+	if opts.DryRun {
+		err = importRows(ps.productRepo)
+	} else {
+		err = ps.productRepo.Transaction(ctx, importRows)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// importProduct crea o actualiza el producto de una fila valida y devuelve el
+// estado de la fila y el ID del producto. En DryRun no escribe nada y las
+// filas que crearian un producto no tienen ID.
+func importProduct(ctx context.Context, repo repository.ProductRepository, req *models.UpdateProductRequest, opts ImportOptions, changeSetID string) (string, uint, error) {
+	product, err := repo.GetByName(ctx, *req.Name)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if opts.DryRun {
+			return ImportStatusCreated, 0, nil
+		}
+		product, err := createImportedProduct(ctx, repo, req, opts.Actor, changeSetID)
+		if err != nil {
+			return "", 0, err
+		}
+		return ImportStatusCreated, product.ID, nil
+	case err != nil:
+		return "", 0, err
+	}
+
+	changed := false
+	if opts.DryRun {
+		history := productUpdateHistory(product, req, opts.Actor, changeSetID)
+		changed = history.HasChanges()
+	} else if changed, err = applyProductUpdate(ctx, repo, product, req, opts.Actor, changeSetID); err != nil {
+		return "", 0, err
+	}
+	if changed {
+		return ImportStatusUpdated, product.ID, nil
+	}
+	return ImportStatusSkipped, product.ID, nil
+}
+
+// createImportedProduct crea el producto con todos los campos de req y registra
+// su creacion como una unica entrada de historial que parte de un estado vacio.
+func createImportedProduct(ctx context.Context, repo repository.ProductRepository, req *models.UpdateProductRequest, actor, changeSetID string) (*models.Product, error) {
+	product := &models.Product{}
+	history := productUpdateHistory(product, req, actor, changeSetID)
+	if err := repo.Create(ctx, product); err != nil {
+		return nil, err
+	}
+	if len(history.NewCategoryIDs) > 0 {
+		if err := repo.UpdateCategories(ctx, product, history.NewCategoryIDs); err != nil {
+			return nil, err
+		}
+		history.NewCategoryIDs = product.CategoryIDs()
+	}
+	history.ProductID = product.ID
+	history.ChangedAt = product.CreatedAt
+	return product, repo.SaveHistory(ctx, &history)
+}
+
+func (row *importRow) toUpdateRequest(categoryIDs map[string]uint) *models.UpdateProductRequest {
+	name := row.name
+	reason := "importación masiva"
+	req := &models.UpdateProductRequest{
+		Name:        &name,
+		Description: row.description,
+		Price:       row.price,
+		Stock:       row.stock,
+		Reason:      &reason,
+	}
+	if row.categories != nil {
+		ids := make([]uint, 0, len(*row.categories))
+		for _, c := range *row.categories {
+			ids = append(ids, categoryIDs[c])
+		}
+		req.Categories = &ids
+	}
+	return req
+}
+
+func validateImportRow(row *importRow, categoryIDs map[string]uint, seen map[string]int) {
+	if row.name == "" {
+		row.fail("import.name_required", nil)
+	} else if line, ok := seen[row.name]; ok {
+		row.fail("import.duplicate_name", map[string]any{"line": line})
+	} else {
+		seen[row.name] = row.line
+	}
+	if row.price != nil && *row.price < 0 {
+		row.fail("import.negative_price", nil)
+	}
+	if row.stock != nil && *row.stock < 0 {
+		row.fail("import.negative_stock", nil)
+	}
+	if row.categories != nil {
+		for _, c := range *row.categories {
+			if _, ok := categoryIDs[c]; !ok {
+				row.fail("import.category_not_found", map[string]any{"category": c})
+			}
+		}
+	}
+}
+
+func resolveImportCategories(ctx context.Context, repo repository.ProductRepository, rows []importRow) (map[string]uint, error) {
+	unique := map[string]bool{}
+	var names []string
+	for _, row := range rows {
+		if row.categories == nil {
+			continue
+		}
+		for _, c := range *row.categories {
+			if !unique[c] {
+				unique[c] = true
+				names = append(names, c)
+			}
+		}
+	}
+
+	categories, err := repo.FindCategoriesByName(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]uint, len(categories))
+	for _, c := range categories {
+		ids[c.Name] = c.ID
+	}
+	return ids, nil
+}
+
+func parseImportCSV(r io.Reader, mapping map[string]string) ([]importRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, apperrors.Validation(apperrors.CodeInvalidFile, "no se pudo leer el encabezado del CSV: %v", err).Wrap(err)
+	}
+
+	fields := make([]string, len(header))
+	hasName := false
+	for i, column := range header {
+		column = strings.TrimSpace(strings.TrimPrefix(column, "\ufeff"))
+		field, ok := mapping[column]
+		if !ok {
+			field = importColumnAliases[strings.ToLower(column)]
+		}
+		fields[i] = field
+		hasName = hasName || field == "name"
+	}
+	if !hasName {
+		return nil, apperrors.Validation(apperrors.CodeInvalidFile, "el CSV debe tener una columna para el nombre del producto").WithKey(apperrors.KeyNameColumnMissing)
+	}
+
+	var rows []importRow
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			row := importRow{line: line}
+			row.fail("import.invalid_row", map[string]any{"error": err})
+			rows = append(rows, row)
+			continue
+		}
+
+		row := importRow{line: line}
+		for i, value := range record {
+			if i >= len(fields) || fields[i] == "" {
+				continue
+			}
+			value = strings.TrimSpace(value)
+			switch fields[i] {
+			case "name":
+				row.name = value
+			case "description":
+				row.description = &value
+			case "price":
+				if value == "" {
+					continue
+				}
+				price, err := strconv.ParseFloat(value, 64)
+				if err != nil {
+					row.fail("import.invalid_price", map[string]any{"value": value})
+					continue
+				}
+				row.price = &price
+			case "stock":
+				if value == "" {
+					continue
+				}
+				stock, err := strconv.Atoi(value)
+				if err != nil {
+					row.fail("import.invalid_stock", map[string]any{"value": value})
+					continue
+				}
+				row.stock = &stock
+			case "categories":
+				categories := splitCategoryNames(value)
+				row.categories = &categories
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// splitCategoryNames separa los nombres de categorias de una celda CSV, que
+// pueden venir separados por "|" o ";".
+func splitCategoryNames(value string) []string {
+	names := []string{}
+	for _, name := range strings.FieldsFunc(value, func(r rune) bool { return r == '|' || r == ';' }) {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+type importRecord struct {
+	Name        string    `json:"name"`
+	Description *string   `json:"description"`
+	Price       *float64  `json:"price"`
+	Stock       *int      `json:"stock"`
+	Categories  *[]string `json:"categories"`
+}
+
+func parseImportNDJSON(r io.Reader) ([]importRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var rows []importRow
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var record importRecord
+		if err := json.Unmarshal([]byte(text), &record); err != nil {
+			row := importRow{line: line}
+			row.fail("import.invalid_json", map[string]any{"error": err})
+			rows = append(rows, row)
+			continue
+		}
+		rows = append(rows, importRow{
+			line:        line,
+			name:        strings.TrimSpace(record.Name),
+			description: record.Description,
+			price:       record.Price,
+			stock:       record.Stock,
+			categories:  record.Categories,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
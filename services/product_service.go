@@ -1,44 +1,68 @@
 package services
 
 import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"qisur-challenge/apperrors"
+	"qisur-challenge/dbdialect"
 	"qisur-challenge/models"
 	"qisur-challenge/repository"
+	"qisur-challenge/tracing"
 	"time"
 
 	"gorm.io/gorm"
 )
 
 type ProductService interface {
-	CreateProduct(product *models.Product) error
-	GetAllProducts() ([]models.Product, error)
-	GetProductByID(id uint) (*models.Product, error)
+	CreateProduct(ctx context.Context, product *models.Product) error
+	GetAllProducts(ctx context.Context) ([]models.Product, error)
+	GetProductByID(ctx context.Context, id uint) (*models.Product, error)
 	ConvertToProductDTO(product *models.Product) models.ProductDTO
 	ConvertToProductDTOs(products []models.Product) []models.ProductDTO
-	UpdateProduct(id uint, req *models.UpdateProductRequest) (*models.Product, error)
-	DeleteProduct(product *models.Product) error
-	GetProductHistory(id uint, start, end *time.Time) ([]models.ProductHistory, error)
-	SearchProducts(name, sort string, page, limit int) ([]models.Product, error)
-	SearchCategories(name, sort string, page, limit int) ([]models.Category, error)
+	UpdateProduct(ctx context.Context, id uint, req *models.UpdateProductRequest, actor string) (*models.Product, bool, error)
+	DeleteProduct(ctx context.Context, product *models.Product) error
+	GetProductHistory(ctx context.Context, id uint, start, end *time.Time) ([]models.ProductHistory, error)
+	GetProductHistoryStats(ctx context.Context, id uint, interval string, start, end *time.Time, fill bool) (*models.HistoryStatsDTO, error)
+	GetProductAsOf(ctx context.Context, id uint, asOf time.Time) (*models.Product, error)
+	RevertProduct(ctx context.Context, id, historyID uint, actor string) (*models.Product, bool, error)
+	ImportProducts(ctx context.Context, r io.Reader, opts ImportOptions) (*ImportReport, error)
+	ExportProducts(ctx context.Context, w io.Writer, opts ExportOptions) error
+	SearchProducts(ctx context.Context, name, sort string, page, limit int) ([]models.Product, error)
+	GetLowStockProducts(ctx context.Context, threshold int) ([]models.Product, error)
+	SchedulePriceChange(ctx context.Context, id uint, req *models.SchedulePriceChangeRequest, actor string) (*models.ScheduledPriceChange, error)
+	GetScheduledPriceChanges(ctx context.Context, id uint) ([]models.ScheduledPriceChange, error)
+	ApplyDuePriceChanges(ctx context.Context, at time.Time) ([]models.Product, error)
+	SearchCategories(ctx context.Context, name, sort string, page, limit int) ([]models.Category, error)
 }
 
 type productService struct {
-	productRepo repository.ProductRepository
-	db          *gorm.DB
+	productRepo     repository.ProductRepository
+	priceChangeRepo repository.PriceChangeRepository
+	db              *gorm.DB
 }
 
 func NewProductService(db *gorm.DB) *productService {
 	return &productService{
-		productRepo: repository.NewProductRepository(db),
-		db:          db,
+		productRepo:     repository.NewProductRepository(db),
+		priceChangeRepo: repository.NewPriceChangeRepository(db),
+		db:              db,
 	}
 }
 
-func (ps *productService) GetAllProducts() ([]models.Product, error) {
-	return ps.productRepo.GetAll()
+func (ps *productService) GetAllProducts(ctx context.Context) (_ []models.Product, err error) {
+	ctx, span := tracing.Start(ctx, "ProductService.GetAllProducts")
+	defer func() { tracing.End(span, err) }()
+
+	return ps.productRepo.GetAll(ctx)
 }
 
-func (ps *productService) GetProductByID(id uint) (*models.Product, error) {
-	return ps.productRepo.GetByID(id)
+func (ps *productService) GetProductByID(ctx context.Context, id uint) (_ *models.Product, err error) {
+	ctx, span := tracing.Start(ctx, "ProductService.GetProductByID")
+	defer func() { tracing.End(span, err) }()
+
+	return ps.productRepo.GetByID(ctx, id)
 }
 
 func (ps *productService) ConvertToProductDTO(product *models.Product) models.ProductDTO {
@@ -69,16 +93,75 @@ func (s *productService) ConvertToProductDTOs(products []models.Product) []model
 	return dtos
 }
 
-func (ps *productService) CreateProduct(product *models.Product) error {
-	return ps.productRepo.Create(product)
+func (ps *productService) CreateProduct(ctx context.Context, product *models.Product) (err error) {
+	ctx, span := tracing.Start(ctx, "ProductService.CreateProduct")
+	defer func() { tracing.End(span, err) }()
+
+	return ps.productRepo.Create(ctx, product)
 }
 
-func (ps *productService) UpdateProduct(id uint, req *models.UpdateProductRequest) (*models.Product, error) {
-	product, err := ps.productRepo.GetByID(id)
+// UpdateProduct aplica los cambios del request y devuelve si efectivamente se
+// modifico algun campo. Las actualizaciones sin cambios no se guardan ni
+// generan historial.
+func (ps *productService) UpdateProduct(ctx context.Context, id uint, req *models.UpdateProductRequest, actor string) (_ *models.Product, _ bool, err error) {
+	ctx, span := tracing.Start(ctx, "ProductService.UpdateProduct")
+	defer func() { tracing.End(span, err) }()
+
+	var product *models.Product
+	changed := false
+	err = ps.productRepo.Transaction(ctx, func(repo repository.ProductRepository) error {
+		var err error
+		product, err = repo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		changed, err = applyProductUpdate(ctx, repo, product, req, actor, NewChangeSetID())
+		return err
+	})
 	if err != nil {
-		return nil, err
+		return nil, false, err
+	}
+	return product, changed, nil
+}
+
+// applyProductUpdate modifica el producto con los campos presentes en req y,
+// si algo cambio, lo guarda junto con su entrada de historial. Debe llamarse
+// con un repositorio dentro de una transaccion.
+func applyProductUpdate(ctx context.Context, repo repository.ProductRepository, product *models.Product, req *models.UpdateProductRequest, actor, changeSetID string) (bool, error) {
+	history := productUpdateHistory(product, req, actor, changeSetID)
+	if !history.HasChanges() {
+		return false, nil
+	}
+
+	if !history.OldCategoryIDs.Equal(history.NewCategoryIDs) {
+		if err := repo.UpdateCategories(ctx, product, history.NewCategoryIDs); err != nil {
+			return false, err
+		}
+		history.NewCategoryIDs = product.CategoryIDs()
+	}
+	if err := repo.Update(ctx, product); err != nil {
+		return false, err
+	}
+	return true, repo.SaveHistory(ctx, &history)
+}
+
+// productUpdateHistory aplica en memoria los campos presentes en req y
+// devuelve la entrada de historial del cambio, sin guardar nada. Las
+// categorias del producto no se modifican.
+func productUpdateHistory(product *models.Product, req *models.UpdateProductRequest, actor, changeSetID string) models.ProductHistory {
+	history := models.ProductHistory{
+		ProductID:      product.ID,
+		ChangeSetID:    changeSetID,
+		Actor:          actor,
+		OldName:        product.Name,
+		OldDescription: product.Description,
+		OldPrice:       product.Price,
+		OldStock:       product.Stock,
+		OldCategoryIDs: product.CategoryIDs(),
+	}
+	if req.Reason != nil {
+		history.Reason = *req.Reason
 	}
-	original := *product
 
 	if req.Name != nil {
 		product.Name = *req.Name
@@ -93,67 +176,209 @@ func (ps *productService) UpdateProduct(id uint, req *models.UpdateProductReques
 		product.Stock = *req.Stock
 	}
 
+	history.NewName = product.Name
+	history.NewDescription = product.Description
+	history.NewPrice = product.Price
+	history.NewStock = product.Stock
+	history.NewCategoryIDs = history.OldCategoryIDs
 	if req.Categories != nil {
-		if err := ps.productRepo.UpdateCategories(product, *req.Categories); err != nil {
-			return nil, err
-		}
+		history.NewCategoryIDs = *req.Categories
 	}
+	return history
+}
+
+func (ps *productService) DeleteProduct(ctx context.Context, product *models.Product) (err error) {
+	ctx, span := tracing.Start(ctx, "ProductService.DeleteProduct")
+	defer func() { tracing.End(span, err) }()
+
+	return ps.productRepo.Delete(ctx, product)
+}
+
+func (ps *productService) GetProductHistory(ctx context.Context, id uint, start, end *time.Time) (_ []models.ProductHistory, err error) {
+	ctx, span := tracing.Start(ctx, "ProductService.GetProductHistory")
+	defer func() { tracing.End(span, err) }()
+
+	return ps.productRepo.GetHistory(ctx, id, start, end)
+}
+
+// GetProductAsOf reconstruye el estado del producto en el instante indicado a
+// partir de su historial.
+func (ps *productService) GetProductAsOf(ctx context.Context, id uint, asOf time.Time) (_ *models.Product, err error) {
+	ctx, span := tracing.Start(ctx, "ProductService.GetProductAsOf")
+	defer func() { tracing.End(span, err) }()
 
-	if err := ps.db.Save(&product).Error; err != nil {
+	product, err := ps.productRepo.GetByID(ctx, id)
+	if err != nil {
 		return nil, err
 	}
 
-	if err := ps.productRepo.SaveHistory(&original); err != nil {
+	before, err := ps.productRepo.LastHistoryBefore(ctx, id, asOf)
+	if err != nil {
 		return nil, err
 	}
+	var categoryIDs models.IDList
+	switch {
+	case before != nil:
+		product.Name = before.NewName
+		product.Description = before.NewDescription
+		product.Price = before.NewPrice
+		product.Stock = before.NewStock
+		categoryIDs = before.NewCategoryIDs
+	case product.CreatedAt.After(asOf):
+		return nil, apperrors.NotFound(apperrors.CodeProductNotFound, "El producto no existía en la fecha indicada").With("as_of", asOf).WithKey(apperrors.KeyProductNotFoundAt).Wrap(gorm.ErrRecordNotFound)
+	default:
+		after, err := ps.productRepo.FirstHistoryAfter(ctx, id, asOf)
+		if err != nil {
+			return nil, err
+		}
+		if after == nil {
+			return product, nil
+		}
+		product.Name = after.OldName
+		product.Description = after.OldDescription
+		product.Price = after.OldPrice
+		product.Stock = after.OldStock
+		categoryIDs = after.OldCategoryIDs
+	}
 
+	categories, err := ps.productRepo.FindCategories(ctx, categoryIDs)
+	if err != nil {
+		return nil, err
+	}
+	product.Categories = categories
 	return product, nil
 }
 
-func (ps *productService) DeleteProduct(product *models.Product) error {
-	return ps.productRepo.Delete(product)
-}
+// RevertProduct deshace la modificacion indicada restaurando el estado previo
+// a ella. La restauracion se registra como un nuevo cambio en el historial.
+func (ps *productService) RevertProduct(ctx context.Context, id, historyID uint, actor string) (_ *models.Product, _ bool, err error) {
+	ctx, span := tracing.Start(ctx, "ProductService.RevertProduct")
+	defer func() { tracing.End(span, err) }()
 
-func (ps *productService) GetProductHistory(id uint, start, end *time.Time) ([]models.ProductHistory, error) {
-	var history []models.ProductHistory
-	query := ps.db.Where("product_id = ?", id)
+	history, err := ps.productRepo.GetHistoryByID(ctx, id, historyID)
+	if err != nil {
+		return nil, false, err
+	}
 
-	if start != nil {
-		query = query.Where("changed_at >= ?", *start)
+	categories := []uint(history.OldCategoryIDs)
+	reason := fmt.Sprintf("revert del cambio %d (%s)", history.ID, history.ChangeSetID)
+	req := models.UpdateProductRequest{
+		Name:        &history.OldName,
+		Description: &history.OldDescription,
+		Price:       &history.OldPrice,
+		Stock:       &history.OldStock,
+		Categories:  &categories,
+		Reason:      &reason,
 	}
-	if end != nil {
-		query = query.Where("changed_at <= ?", *end)
+	return ps.UpdateProduct(ctx, id, &req, actor)
+}
+
+// NewChangeSetID genera un identificador aleatorio con formato UUID v4
+// para agrupar las entradas de historial de una misma modificacion.
+func NewChangeSetID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
 	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
 
-	err := query.Find(&history).Error
-	return history, err
+func (ps *productService) SearchProducts(ctx context.Context, name, sort string, page, limit int) (_ []models.Product, err error) {
+	ctx, span := tracing.Start(ctx, "ProductService.SearchProducts")
+	defer func() { tracing.End(span, err) }()
+
+	return ps.productRepo.Search(ctx, repository.ProductFilter{Name: name, Sort: sort}, page, limit)
 }
 
-func (ps *productService) SearchProducts(name, sort string, page, limit int) ([]models.Product, error) {
-	db := ps.db.Model(&models.Product{})
+func (ps *productService) GetLowStockProducts(ctx context.Context, threshold int) (_ []models.Product, err error) {
+	ctx, span := tracing.Start(ctx, "ProductService.GetLowStockProducts")
+	defer func() { tracing.End(span, err) }()
 
-	if name != "" {
-		db = db.Where("name ILIKE ?", "%"+name+"%")
+	return ps.productRepo.GetLowStock(ctx, threshold)
+}
+
+func (ps *productService) SchedulePriceChange(ctx context.Context, id uint, req *models.SchedulePriceChangeRequest, actor string) (_ *models.ScheduledPriceChange, err error) {
+	ctx, span := tracing.Start(ctx, "ProductService.SchedulePriceChange")
+	defer func() { tracing.End(span, err) }()
+
+	if req.Price == nil || *req.Price < 0 {
+		return nil, apperrors.Validation(apperrors.CodeValidation, "El precio es obligatorio y no puede ser negativo").With("field", "price").WithKey(apperrors.KeyPriceRequired)
+	}
+	if req.EffectiveAt == nil {
+		return nil, apperrors.Validation(apperrors.CodeValidation, "La fecha 'effective_at' es obligatoria (RFC3339)").With("field", "effective_at").WithKey(apperrors.KeyEffectiveAtRequired)
+	}
+	if _, err := ps.productRepo.GetByID(ctx, id); err != nil {
+		return nil, err
 	}
 
-	switch sort {
-	case "price_asc":
-		db = db.Order("price ASC")
-	case "price_desc":
-		db = db.Order("price DESC")
+	change := &models.ScheduledPriceChange{
+		ProductID:   id,
+		Price:       *req.Price,
+		EffectiveAt: *req.EffectiveAt,
+		Reason:      req.Reason,
+		CreatedBy:   actor,
+	}
+	if err := ps.priceChangeRepo.Create(ctx, change); err != nil {
+		return nil, err
 	}
+	return change, nil
+}
 
-	offset := (page - 1) * limit
-	var products []models.Product
-	err := db.Offset(offset).Limit(limit).Find(&products).Error
-	return products, err
+func (ps *productService) GetScheduledPriceChanges(ctx context.Context, id uint) (_ []models.ScheduledPriceChange, err error) {
+	ctx, span := tracing.Start(ctx, "ProductService.GetScheduledPriceChanges")
+	defer func() { tracing.End(span, err) }()
+
+	return ps.priceChangeRepo.ListByProduct(ctx, id)
 }
 
-func (ps *productService) SearchCategories(name, sort string, page, limit int) ([]models.Category, error) {
-	db := ps.db.Model(&models.Category{})
+// ApplyDuePriceChanges aplica los cambios de precio programados cuya fecha ya
+// paso, registrandolos en el historial como cualquier otra actualizacion.
+// Devuelve los productos que efectivamente cambiaron.
+func (ps *productService) ApplyDuePriceChanges(ctx context.Context, at time.Time) (_ []models.Product, err error) {
+	ctx, span := tracing.Start(ctx, "ProductService.ApplyDuePriceChanges")
+	defer func() { tracing.End(span, err) }()
+
+	changes, err := ps.priceChangeRepo.Due(ctx, at)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []models.Product
+	for _, change := range changes {
+		price := change.Price
+		reason := change.Reason
+		if reason == "" {
+			reason = fmt.Sprintf("cambio de precio programado %d", change.ID)
+		}
+		actor := change.CreatedBy
+		if actor == "" {
+			actor = "scheduler"
+		}
+
+		errMsg := ""
+		product, changed, err := ps.UpdateProduct(ctx, change.ProductID, &models.UpdateProductRequest{Price: &price, Reason: &reason}, actor)
+		if err != nil {
+			errMsg = err.Error()
+		} else if changed {
+			applied = append(applied, *product)
+		}
+		if err := ps.priceChangeRepo.MarkApplied(ctx, change.ID, time.Now(), errMsg); err != nil {
+			return applied, err
+		}
+	}
+	return applied, nil
+}
+
+func (ps *productService) SearchCategories(ctx context.Context, name, sort string, page, limit int) (_ []models.Category, err error) {
+	ctx, span := tracing.Start(ctx, "ProductService.SearchCategories")
+	defer func() { tracing.End(span, err) }()
+
+	db := ps.db.WithContext(ctx).Model(&models.Category{})
 
 	if name != "" {
-		db = db.Where("name ILIKE ?", "%"+name+"%")
+		db = db.Where(dbdialect.ContainsFold(db, "name"), "%"+name+"%")
 	}
 
 	switch sort {
@@ -165,6 +390,6 @@ func (ps *productService) SearchCategories(name, sort string, page, limit int) (
 
 	offset := (page - 1) * limit
 	var categories []models.Category
-	err := db.Offset(offset).Limit(limit).Find(&categories).Error
+	err = db.Offset(offset).Limit(limit).Find(&categories).Error
 	return categories, err
 }
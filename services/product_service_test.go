@@ -0,0 +1,431 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"qisur-challenge/apperrors"
+	"qisur-challenge/i18n"
+	"qisur-challenge/models"
+	"qisur-challenge/repository"
+	"qisur-challenge/repository/memory"
+	"qisur-challenge/services"
+
+	"gorm.io/gorm"
+)
+
+type productFixture struct {
+	service    services.ProductService
+	categories repository.CategoryRepository
+}
+
+func newProductFixture(t *testing.T) *productFixture {
+	t.Helper()
+	store := memory.NewStore()
+	return &productFixture{
+		service:    services.NewProductServiceWithRepositories(memory.NewProductRepository(store), nil),
+		categories: memory.NewCategoryRepository(store),
+	}
+}
+
+func (f *productFixture) category(t *testing.T, name string) models.Category {
+	t.Helper()
+	category := models.Category{Name: name}
+	if err := f.categories.Create(context.Background(), &category); err != nil {
+		t.Fatalf("creando categoria %q: %v", name, err)
+	}
+	return category
+}
+
+func (f *productFixture) product(t *testing.T, name string, price float64, stock int, categories ...models.Category) models.Product {
+	t.Helper()
+	product := models.Product{Name: name, Price: price, Stock: stock, Categories: categories}
+	if err := f.service.CreateProduct(context.Background(), &product); err != nil {
+		t.Fatalf("creando producto %q: %v", name, err)
+	}
+	return product
+}
+
+func (f *productFixture) history(t *testing.T, id uint) []models.ProductHistory {
+	t.Helper()
+	history, err := f.service.GetProductHistory(context.Background(), id, nil, nil)
+	if err != nil {
+		t.Fatalf("obteniendo historial: %v", err)
+	}
+	return history
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// assertCode verifica que err sea un error de dominio con el codigo indicado.
+func assertCode(t *testing.T, err error, code string) {
+	t.Helper()
+	if !errors.Is(err, &apperrors.Error{Code: code}) {
+		t.Fatalf("error = %v, se esperaba el codigo %q", err, code)
+	}
+}
+
+func TestCreateProduct(t *testing.T) {
+	tests := []struct {
+		name     string
+		product  string
+		wantCode string
+	}{
+		{name: "nombre nuevo", product: "Teclado"},
+		{name: "nombre repetido", product: "Mouse", wantCode: apperrors.CodeProductNameTaken},
+		{name: "distinto en mayusculas", product: "MOUSE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newProductFixture(t)
+			f.product(t, "Mouse", 10, 5)
+
+			product := models.Product{Name: tt.product, Price: 20}
+			err := f.service.CreateProduct(context.Background(), &product)
+			if tt.wantCode != "" {
+				assertCode(t, err, tt.wantCode)
+				return
+			}
+			if err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			if product.ID == 0 || product.CreatedAt.IsZero() {
+				t.Errorf("no se asignaron ID y fecha de creacion: %+v", product)
+			}
+			if got := f.history(t, product.ID); len(got) != 0 {
+				t.Errorf("la creacion no debe registrar historial, hay %d entradas", len(got))
+			}
+		})
+	}
+}
+
+func TestUpdateProduct(t *testing.T) {
+	tests := []struct {
+		name        string
+		req         func(t *testing.T, f *productFixture) models.UpdateProductRequest
+		wantCode    string
+		wantChanged bool
+		check       func(t *testing.T, p *models.Product)
+		checkEntry  func(t *testing.T, h models.ProductHistory)
+	}{
+		{
+			name: "solo precio",
+			req: func(*testing.T, *productFixture) models.UpdateProductRequest {
+				return models.UpdateProductRequest{Price: ptr(15.5)}
+			},
+			wantChanged: true,
+			check: func(t *testing.T, p *models.Product) {
+				if p.Name != "Mouse" || p.Description != "inalambrico" || p.Price != 15.5 || p.Stock != 5 {
+					t.Errorf("solo debia cambiar el precio: %+v", p)
+				}
+			},
+			checkEntry: func(t *testing.T, h models.ProductHistory) {
+				if h.OldPrice != 10 || h.NewPrice != 15.5 || h.OldName != "Mouse" || h.NewName != "Mouse" {
+					t.Errorf("historial incorrecto: %+v", h)
+				}
+			},
+		},
+		{
+			name: "nombre y stock con motivo",
+			req: func(*testing.T, *productFixture) models.UpdateProductRequest {
+				return models.UpdateProductRequest{Name: ptr("Mouse Pro"), Stock: ptr(0), Reason: ptr("reposicion")}
+			},
+			wantChanged: true,
+			check: func(t *testing.T, p *models.Product) {
+				if p.Name != "Mouse Pro" || p.Stock != 0 || p.Price != 10 {
+					t.Errorf("actualizacion parcial incorrecta: %+v", p)
+				}
+			},
+			checkEntry: func(t *testing.T, h models.ProductHistory) {
+				if h.Reason != "reposicion" || h.Actor != "ana" || h.OldStock != 5 || h.NewStock != 0 {
+					t.Errorf("historial incorrecto: %+v", h)
+				}
+			},
+		},
+		{
+			name: "categorias",
+			req: func(t *testing.T, f *productFixture) models.UpdateProductRequest {
+				c := f.category(t, "Gaming")
+				return models.UpdateProductRequest{Categories: &[]uint{c.ID}}
+			},
+			wantChanged: true,
+			check: func(t *testing.T, p *models.Product) {
+				if len(p.Categories) != 1 || p.Categories[0].Name != "Gaming" {
+					t.Errorf("categorias incorrectas: %+v", p.Categories)
+				}
+			},
+			checkEntry: func(t *testing.T, h models.ProductHistory) {
+				if len(h.OldCategoryIDs) != 1 || len(h.NewCategoryIDs) != 1 || h.OldCategoryIDs.Equal(h.NewCategoryIDs) {
+					t.Errorf("categorias del historial incorrectas: %v -> %v", h.OldCategoryIDs, h.NewCategoryIDs)
+				}
+			},
+		},
+		{
+			name: "mismos valores",
+			req: func(*testing.T, *productFixture) models.UpdateProductRequest {
+				return models.UpdateProductRequest{Name: ptr("Mouse"), Price: ptr(10.0)}
+			},
+			wantChanged: false,
+		},
+		{
+			name: "sin campos",
+			req: func(*testing.T, *productFixture) models.UpdateProductRequest {
+				return models.UpdateProductRequest{}
+			},
+			wantChanged: false,
+		},
+		{
+			name: "nombre repetido",
+			req: func(*testing.T, *productFixture) models.UpdateProductRequest {
+				return models.UpdateProductRequest{Name: ptr("Teclado"), Price: ptr(99.0)}
+			},
+			wantCode: apperrors.CodeProductNameTaken,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newProductFixture(t)
+			ctx := context.Background()
+			perifericos := f.category(t, "Perifericos")
+			mouse := models.Product{Name: "Mouse", Description: "inalambrico", Price: 10, Stock: 5, Categories: []models.Category{perifericos}}
+			if err := f.service.CreateProduct(ctx, &mouse); err != nil {
+				t.Fatal(err)
+			}
+			f.product(t, "Teclado", 30, 2)
+
+			req := tt.req(t, f)
+			product, changed, err := f.service.UpdateProduct(ctx, mouse.ID, &req, "ana")
+			history := f.history(t, mouse.ID)
+
+			if tt.wantCode != "" {
+				assertCode(t, err, tt.wantCode)
+				stored, _ := f.service.GetProductByID(ctx, mouse.ID)
+				if stored.Name != "Mouse" || stored.Price != 10 {
+					t.Errorf("un error no debe guardar cambios: %+v", stored)
+				}
+				if len(history) != 0 {
+					t.Errorf("un error no debe registrar historial, hay %d entradas", len(history))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error inesperado: %v", err)
+			}
+			if changed != tt.wantChanged {
+				t.Errorf("changed = %v, se esperaba %v", changed, tt.wantChanged)
+			}
+			if !tt.wantChanged {
+				if len(history) != 0 {
+					t.Errorf("una actualizacion sin cambios no debe registrar historial, hay %d entradas", len(history))
+				}
+				return
+			}
+
+			stored, err := f.service.GetProductByID(ctx, mouse.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, product)
+			tt.check(t, stored)
+			if len(history) != 1 {
+				t.Fatalf("se esperaba 1 entrada de historial, hay %d", len(history))
+			}
+			if history[0].ChangeSetID == "" || history[0].ChangedAt.IsZero() {
+				t.Errorf("entrada sin change set o fecha: %+v", history[0])
+			}
+			tt.checkEntry(t, history[0])
+		})
+	}
+}
+
+func TestUpdateProductNotFound(t *testing.T) {
+	f := newProductFixture(t)
+	_, _, err := f.service.UpdateProduct(context.Background(), 42, &models.UpdateProductRequest{Price: ptr(1.0)}, "ana")
+	assertCode(t, err, apperrors.CodeProductNotFound)
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("error = %v, debe seguir cumpliendo errors.Is(err, gorm.ErrRecordNotFound)", err)
+	}
+}
+
+func TestRevertProduct(t *testing.T) {
+	f := newProductFixture(t)
+	ctx := context.Background()
+	mouse := f.product(t, "Mouse", 10, 5)
+
+	if _, _, err := f.service.UpdateProduct(ctx, mouse.ID, &models.UpdateProductRequest{Name: ptr("Mouse Pro"), Price: ptr(25.0)}, "ana"); err != nil {
+		t.Fatal(err)
+	}
+	first := f.history(t, mouse.ID)[0]
+
+	product, changed, err := f.service.RevertProduct(ctx, mouse.ID, first.ID, "juan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed || product.Name != "Mouse" || product.Price != 10 {
+		t.Errorf("el revert no restauro el estado previo: changed=%v %+v", changed, product)
+	}
+
+	history := f.history(t, mouse.ID)
+	if len(history) != 2 {
+		t.Fatalf("se esperaban 2 entradas de historial, hay %d", len(history))
+	}
+	if history[1].Actor != "juan" || !strings.Contains(history[1].Reason, "revert") {
+		t.Errorf("la entrada del revert es incorrecta: %+v", history[1])
+	}
+
+	_, _, err = f.service.RevertProduct(ctx, mouse.ID, 9999, "juan")
+	assertCode(t, err, apperrors.CodeHistoryNotFound)
+}
+
+func TestGetProductAsOf(t *testing.T) {
+	f := newProductFixture(t)
+	ctx := context.Background()
+	mouse := f.product(t, "Mouse", 10, 5)
+	beforeUpdate := time.Now()
+
+	if _, _, err := f.service.UpdateProduct(ctx, mouse.ID, &models.UpdateProductRequest{Price: ptr(20.0)}, "ana"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		asOf      time.Time
+		wantPrice float64
+		wantCode  string
+	}{
+		{name: "antes de la creacion", asOf: mouse.CreatedAt.Add(-time.Hour), wantCode: apperrors.CodeProductNotFound},
+		{name: "antes del cambio", asOf: beforeUpdate, wantPrice: 10},
+		{name: "despues del cambio", asOf: time.Now().Add(time.Hour), wantPrice: 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			product, err := f.service.GetProductAsOf(ctx, mouse.ID, tt.asOf)
+			if tt.wantCode != "" {
+				assertCode(t, err, tt.wantCode)
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if product.Price != tt.wantPrice {
+				t.Errorf("precio = %v, se esperaba %v", product.Price, tt.wantPrice)
+			}
+		})
+	}
+}
+
+func TestGetProductHistoryStatsDefaultRange(t *testing.T) {
+	f := newProductFixture(t)
+	ctx := context.Background()
+	created := time.Now().AddDate(0, 0, -300)
+	product := models.Product{Name: "Monitor", Price: 100, Stock: 3, CreatedAt: created}
+	if err := f.service.CreateProduct(ctx, &product); err != nil {
+		t.Fatal(err)
+	}
+
+	// Sin start se devuelven los ultimos intervalos aunque el producto tenga
+	// mas de 5000 horas.
+	stats, err := f.service.GetProductHistoryStats(ctx, product.ID, "hour", nil, nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats.Buckets) != 5000 || !stats.Start.After(created) {
+		t.Errorf("start = %v con %d intervalos, se esperaban los ultimos 5000", stats.Start, len(stats.Buckets))
+	}
+
+	// Con start explicito el rango se respeta y se rechaza si es demasiado largo.
+	_, err = f.service.GetProductHistoryStats(ctx, product.ID, "hour", &created, nil, false)
+	assertCode(t, err, apperrors.CodeValidation)
+}
+
+func TestImportProductsLocalizedErrors(t *testing.T) {
+	csv := "name,price,stock,categories\nMouse,-1,2,\n,3,x,Audio\n"
+	tests := []struct {
+		lang i18n.Lang
+		want [][]string
+	}{
+		{
+			lang: i18n.Spanish,
+			want: [][]string{
+				{"el precio no puede ser negativo"},
+				{"stock inválido: x", "el nombre es obligatorio", "categoría 'Audio' no encontrada"},
+			},
+		},
+		{
+			lang: i18n.English,
+			want: [][]string{
+				{"price cannot be negative"},
+				{"invalid stock: x", "name is required", "category 'Audio' not found"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.lang), func(t *testing.T) {
+			f := newProductFixture(t)
+			ctx := i18n.WithLang(context.Background(), tt.lang)
+
+			report, err := f.service.ImportProducts(ctx, strings.NewReader(csv), services.ImportOptions{Format: "csv", DryRun: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(report.Rows) != len(tt.want) {
+				t.Fatalf("filas = %+v", report.Rows)
+			}
+			for i, row := range report.Rows {
+				if strings.Join(row.Errors, "; ") != strings.Join(tt.want[i], "; ") {
+					t.Errorf("fila %d: errores = %q, se esperaba %q", row.Row, row.Errors, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestImportProducts(t *testing.T) {
+	ctx := context.Background()
+	f := newProductFixture(t)
+	audio := f.category(t, "Audio")
+	mouse := f.product(t, "Mouse", 10, 1)
+	csv := "name,price,stock,categories\nMouse,12,1,\nParlante,30,4,Audio\n"
+
+	report, err := f.service.ImportProducts(ctx, strings.NewReader(csv), services.ImportOptions{Format: "csv", DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Updated != 1 || report.Created != 1 || report.Rows[1].ProductID != 0 {
+		t.Errorf("dry-run: reporte = %+v", report)
+	}
+	if got := f.history(t, mouse.ID); len(got) != 0 {
+		t.Errorf("el dry-run no debe registrar historial: %+v", got)
+	}
+	if products, _ := f.service.GetAllProducts(ctx); len(products) != 1 || products[0].Price != 10 {
+		t.Fatalf("el dry-run no debe escribir: %+v", products)
+	}
+
+	report, err = f.service.ImportProducts(ctx, strings.NewReader(csv), services.ImportOptions{Format: "csv", Actor: "admin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := report.Rows[1]
+	if report.Updated != 1 || report.Created != 1 || created.ProductID == 0 {
+		t.Fatalf("reporte = %+v", report)
+	}
+	product, err := f.service.GetProductByID(ctx, created.ProductID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if product.Price != 30 || product.Stock != 4 || !models.IDList(product.CategoryIDs()).Equal(models.IDList{audio.ID}) {
+		t.Errorf("producto creado = %+v", product)
+	}
+	history := f.history(t, created.ProductID)
+	if len(history) != 1 {
+		t.Fatalf("la creacion debe registrar una unica entrada: %+v", history)
+	}
+	if h := history[0]; h.OldName != "" || h.NewName != "Parlante" || h.NewPrice != 30 || h.NewStock != 4 || !h.NewCategoryIDs.Equal(models.IDList{audio.ID}) || h.Actor != "admin" {
+		t.Errorf("historial = %+v", h)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"qisur-challenge/config"
+	"qisur-challenge/scheduler"
+	"qisur-challenge/services"
+	websocket "qisur-challenge/webSocket"
+)
+
+// registerTasks agrega al scheduler las tareas periodicas del servidor.
+func registerTasks(sched *scheduler.Scheduler, retentionService services.HistoryRetentionService, productService services.ProductService) error {
+	cfg := config.AppConfig
+
+	if retentionService.Enabled() {
+		err := sched.Add("history_prune", cfg.History.PruneSchedule, func(ctx context.Context) error {
+			_, err := retentionService.Run(ctx, cfg.History.PruneDryRun)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	err := sched.Add("low_stock_scan", cfg.Scheduler.LowStockSchedule, func(ctx context.Context) error {
+		products, err := productService.GetLowStockProducts(ctx, cfg.Scheduler.LowStockThreshold)
+		if err != nil {
+			return err
+		}
+		for _, p := range products {
+			websocket.GetEventManager().BroadcastMessage(ctx, websocket.Message{
+				Type: "low_stock",
+				Data: websocket.ProductData{ID: int(p.ID), Name: p.Name},
+			})
+		}
+		if len(products) > 0 {
+			slog.Info("Productos con stock bajo", "count", len(products), "threshold", cfg.Scheduler.LowStockThreshold)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return sched.Add("scheduled_price_changes", cfg.Scheduler.PriceChangesSchedule, func(ctx context.Context) error {
+		products, err := productService.ApplyDuePriceChanges(ctx, time.Now())
+		for _, p := range products {
+			websocket.GetEventManager().BroadcastMessage(ctx, websocket.Message{
+				Type: "product_upgraded",
+				Data: websocket.ProductData{ID: int(p.ID), Name: p.Name},
+			})
+		}
+		return err
+	})
+}
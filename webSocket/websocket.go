@@ -1,117 +1,296 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
+	"sync"
+	"time"
+
+	"qisur-challenge/apperrors"
+	"qisur-challenge/config"
+	"qisur-challenge/logger"
+	"qisur-challenge/metrics"
+	"qisur-challenge/tracing"
+
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// closeWriteTimeout es el tiempo maximo para enviar el frame de cierre.
+const closeWriteTimeout = time.Second
+
+// clientBuffer es la cantidad de mensajes que se encolan por cliente. Un
+// cliente que no los consume a tiempo se desconecta para no frenar al resto.
+const clientBuffer = 64
+
+// client es una conexion con su cola de mensajes, que escribe una goroutine
+// propia para que un cliente lento no bloquee los envios a los demas.
+type client struct {
+	conn *websocket.Conn
+	send chan interface{}
+}
+
 type EventManager struct {
-	clients  map[*websocket.Conn]bool
-	broadcast chan Message
+	mu      sync.Mutex
+	closed  bool
+	clients map[*websocket.Conn]*client
 }
 
 func NewEventManager() *EventManager {
 	return &EventManager{
-		clients:  make(map[*websocket.Conn]bool),
-		broadcast: make(chan Message),
+		clients: make(map[*websocket.Conn]*client),
 	}
 }
 
 var eventManager = NewEventManager()
 
 func (em *EventManager) AddClient(conn *websocket.Conn) {
-	em.clients[conn] = true
+	em.mu.Lock()
+	defer em.mu.Unlock()
+	if em.closed {
+		// El servidor se esta apagando: se rechaza la conexion con el mismo
+		// frame que reciben los clientes existentes.
+		msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "servidor apagándose")
+		conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(closeWriteTimeout))
+		conn.Close()
+		return
+	}
+	c := &client{conn: conn, send: make(chan interface{}, clientBuffer)}
+	em.clients[conn] = c
+	metrics.WSClients.Set(float64(len(em.clients)))
+	go em.write(c)
+}
+
+// write envia los mensajes encolados para c hasta que se lo quite del broker.
+// Si un envio falla se cierra la conexion.
+func (em *EventManager) write(c *client) {
+	for msg := range c.send {
+		if limits.WriteTimeout > 0 {
+			c.conn.SetWriteDeadline(time.Now().Add(limits.WriteTimeout))
+		}
+		if err := c.conn.WriteJSON(msg); err != nil {
+			slog.Warn("Error al enviar mensaje a cliente WebSocket", "remote_addr", c.conn.RemoteAddr().String(), logger.Err(err))
+			metrics.WSMessagesDropped.Inc()
+			em.RemoveClient(c.conn)
+			return
+		}
+		metrics.WSMessagesSent.Inc()
+	}
+}
+
+func (em *EventManager) ClientCount() int {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+	return len(em.clients)
 }
 
+// Closed indica si CloseAll ya se ejecuto y el broker no acepta mas clientes.
+func (em *EventManager) Closed() bool {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+	return em.closed
+}
+
+// RemoveClient quita a conn del broker y la cierra. Puede llamarse mas de una
+// vez para la misma conexion.
 func (em *EventManager) RemoveClient(conn *websocket.Conn) {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+	em.removeLocked(conn)
+	metrics.WSClients.Set(float64(len(em.clients)))
+}
+
+// removeLocked quita a conn del broker. Debe llamarse con em.mu tomado.
+func (em *EventManager) removeLocked(conn *websocket.Conn) {
+	c, ok := em.clients[conn]
+	if !ok {
+		return
+	}
 	delete(em.clients, conn)
+	close(c.send)
+	conn.Close()
 }
 
-func (em *EventManager) BroadcastMessage(msg Message) {
-	for client := range em.clients {
-		err := client.WriteJSON(msg)
-		if err != nil {
-			log.Println("Error al enviar mensaje a cliente:", err)
-			client.Close()
-			em.RemoveClient(client)
+// enqueueLocked encola msg para c sin bloquear. Si la cola esta llena el
+// cliente se desconecta. Debe llamarse con em.mu tomado.
+func (em *EventManager) enqueueLocked(c *client, msg interface{}) bool {
+	select {
+	case c.send <- msg:
+		return true
+	default:
+		slog.Warn("Cliente WebSocket lento, se desconecta", "remote_addr", c.conn.RemoteAddr().String())
+		metrics.WSMessagesDropped.Inc()
+		em.removeLocked(c.conn)
+		return false
+	}
+}
+
+// SendError envia a conn un frame de error para err, por ejemplo cuando no se
+// pudo procesar un mensaje del cliente.
+func (em *EventManager) SendError(ctx context.Context, conn *websocket.Conn, err error) {
+	_, body := apperrors.NewResponse(ctx, err)
+
+	em.mu.Lock()
+	defer em.mu.Unlock()
+	if c, ok := em.clients[conn]; ok {
+		em.enqueueLocked(c, ErrorMessage{Type: "error", Error: body})
+		metrics.WSClients.Set(float64(len(em.clients)))
+	}
+}
+
+// BroadcastMessage encola msg para todos los clientes conectados, sin esperar
+// a que se envie. El span que se crea en ctx permite ver en la traza de la
+// request cuanto tarda.
+func (em *EventManager) BroadcastMessage(ctx context.Context, msg Message) {
+	_, span := tracing.Start(ctx, "websocket.broadcast", trace.WithAttributes(
+		attribute.String("event.type", msg.Type),
+	))
+	defer span.End()
+
+	em.mu.Lock()
+	defer em.mu.Unlock()
+	start := time.Now()
+	dropped := 0
+	for _, c := range em.clients {
+		if !em.enqueueLocked(c, msg) {
+			dropped++
 		}
 	}
+	metrics.WSClients.Set(float64(len(em.clients)))
+	metrics.WSBroadcastDuration.Observe(time.Since(start).Seconds())
+	span.SetAttributes(
+		attribute.Int("websocket.clients", len(em.clients)),
+		attribute.Int("websocket.dropped", dropped),
+	)
+}
+
+// CloseAll cierra todas las conexiones enviando un frame "going away", para
+// que los clientes sepan que el servidor se esta apagando y reconecten.
+func (em *EventManager) CloseAll() {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+	em.closed = true
+	msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "servidor apagándose")
+	for conn := range em.clients {
+		conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(closeWriteTimeout))
+		em.removeLocked(conn)
+	}
+	metrics.WSClients.Set(0)
 }
 
 var upgrader = websocket.Upgrader{
-    CheckOrigin: func(r *http.Request) bool { return true },
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// limits son los limites aplicados con Configure. Sin configurar no se limita.
+var limits config.WebSocketConfig
+
+// Configure aplica los limites de conexiones y mensajes. Si hay origenes CORS
+// configurados, solo se aceptan conexiones de esos origenes.
+func Configure(cfg config.WebSocketConfig, cors config.CORSConfig) {
+	limits = cfg
+	upgrader.CheckOrigin = func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		return origin == "" || len(cors.AllowedOrigins) == 0 || cors.AllowsOrigin(origin)
+	}
 }
 
+// Message es un evento enviado a los clientes. Data es ProductData para los
+// eventos de productos y categorias, y JobData para los de trabajos.
 type Message struct {
-    Type string      `json:"type"`
-    Data ProductData `json:"data"`
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
 }
 
 type ProductData struct {
-    ID   int    `json:"id"`
-    Name string `json:"name"`
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type JobData struct {
+	ID     uint   `json:"id"`
+	Type   string `json:"type"`
+	Status string `json:"status"`
 }
 
+// clientMessage es un mensaje recibido de un cliente, que siempre se refiere a
+// un producto.
+type clientMessage struct {
+	Type string      `json:"type"`
+	Data ProductData `json:"data"`
+}
+
+// ErrorMessage es el frame que recibe un cliente cuando no se pudo procesar un
+// mensaje suyo. Error tiene el mismo formato que las respuestas de error de la
+// API.
+type ErrorMessage struct {
+	Type  string             `json:"type"`
+	Error apperrors.Response `json:"error"`
+}
 
 func HandleWebSocket(w http.ResponseWriter, r *http.Request) {
-    conn, err := upgrader.Upgrade(w, r, nil)
-    if err != nil {
-        log.Println("Error al actualizar a WebSocket:", err)
-        return
-    }
-    defer conn.Close()
-
-    eventManager.AddClient(conn)
-    defer eventManager.RemoveClient(conn)
-
-    go func() {
-        for {
-            select {
-            case msg := <-eventManager.broadcast:
-                eventManager.BroadcastMessage(msg)
-            }
-        }
-    }()
-
-    for {
-        _, msg, err := conn.ReadMessage()
-        if err != nil {
-            log.Println("Error al leer mensaje:", err)
-            break
-        }
-
-        var message Message
-        if err := json.Unmarshal(msg, &message); err != nil {
-            log.Println("Error al parsear mensaje:", err)
-            continue
-        }
-
-        log.Printf("Mensaje recibido: %s\n", message.Data)
-
-        switch message.Type {
-        case "create":
-            eventManager.BroadcastMessage(Message{
-                Type:    "product_created",
-                Data: message.Data,
-            })
-        case "update":
-            eventManager.BroadcastMessage(Message{
-                Type:    "product_updated",
-                Data: message.Data,
-            })
-        case "delete":
-            eventManager.BroadcastMessage(Message{
-                Type:    "product_deleted",
-                Data: message.Data,
-            })
-        default:
-            log.Printf("Tipo de mensaje desconocido: %s", message.Type)
-        }
-    }
+	log := logger.FromContext(r.Context())
+	if limits.MaxClients > 0 && eventManager.ClientCount() >= limits.MaxClients {
+		apperrors.Write(w, r, apperrors.New(apperrors.KindUnavailable, apperrors.CodeWebSocketFull, "Se alcanzó el máximo de conexiones WebSocket"))
+		return
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Warn("Error al actualizar a WebSocket", logger.Err(err))
+		return
+	}
+	defer conn.Close()
+	if limits.MaxMessageBytes > 0 {
+		conn.SetReadLimit(limits.MaxMessageBytes)
+	}
+
+	eventManager.AddClient(conn)
+	defer eventManager.RemoveClient(conn)
+
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Warn("Error al leer mensaje WebSocket", logger.Err(err))
+			}
+			break
+		}
+
+		var message clientMessage
+		if err := json.Unmarshal(msg, &message); err != nil {
+			log.Warn("Error al parsear mensaje WebSocket", logger.Err(err))
+			eventManager.SendError(r.Context(), conn, apperrors.Validation(apperrors.CodeInvalidMessage, "Mensaje inválido: se esperaba JSON con 'type' y 'data'").Wrap(err))
+			continue
+		}
+
+		log.Debug("Mensaje WebSocket recibido", "type", message.Type, "product_id", message.Data.ID)
+
+		switch message.Type {
+		case "create":
+			eventManager.BroadcastMessage(r.Context(), Message{
+				Type: "product_created",
+				Data: message.Data,
+			})
+		case "update":
+			eventManager.BroadcastMessage(r.Context(), Message{
+				Type: "product_updated",
+				Data: message.Data,
+			})
+		case "delete":
+			eventManager.BroadcastMessage(r.Context(), Message{
+				Type: "product_deleted",
+				Data: message.Data,
+			})
+		default:
+			log.Warn("Tipo de mensaje WebSocket desconocido", "type", message.Type)
+			eventManager.SendError(r.Context(), conn, apperrors.Validation(apperrors.CodeUnknownMessage, "Tipo de mensaje desconocido: '%s'", message.Type).With("type", message.Type))
+		}
+	}
 }
 
 func GetEventManager() *EventManager {
 	return eventManager
-}
\ No newline at end of file
+}